@@ -23,6 +23,9 @@ const (
 	dbname     = "projects"
 	dbreset    = false
 
+	// searchBackend serves the projects list from SQL ("sql") or from an in-memory index ("memory").
+	searchBackend = "sql"
+
 	srvhost = "10.7.0.3"
 	srvport = 8081
 )
@@ -65,7 +68,7 @@ func main() {
 	l.Printf("Running server on port %d\n", srvport)
 
 	// Setup services
-	projectsAPI.Activate(ctx, r, db, dbreset)
+	projectsAPI.Activate(ctx, r, db, dbreset, searchBackend)
 
 	go func() {
 		// Initiate the server listening.
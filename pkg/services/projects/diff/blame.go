@@ -0,0 +1,44 @@
+package diff
+
+import "lastimplementation.com/pkg/services/projects/models"
+
+// Blame attributes each line of the current content of a code file to the
+// revision that last changed it, given the revisions of the file from oldest
+// to newest. Lines kept by the diff from one revision to the next keep their
+// revision, while the inserted ones take the new revision. It also tells
+// whether the attribution is approximate, when two revisions were too
+// different to diff within maxEdits and all their differing lines were taken
+// as inserted.
+func Blame(revisions []models.FileRevision, content string) ([]models.BlameRange, bool) {
+	var lines []string
+	var owners []models.RevisionRef
+	approximate := false
+	step := func(next []string, ref models.RevisionRef) {
+		nextOwners := make([]models.RevisionRef, len(next))
+		edits, shortest := boundedScript(lines, next)
+		approximate = approximate || !shortest
+		for _, e := range edits {
+			switch e.op {
+			case models.DiffEqual:
+				nextOwners[e.newIdx] = owners[e.oldIdx]
+			case models.DiffInsert:
+				nextOwners[e.newIdx] = ref
+			}
+		}
+		lines, owners = next, nextOwners
+	}
+	for _, rev := range revisions {
+		step(splitLines(rev.Content), rev.RevisionRef)
+	}
+	step(splitLines(content), models.RevisionRef{})
+
+	res := []models.BlameRange{}
+	for i, owner := range owners {
+		if n := len(res); n > 0 && res[n-1].Revision == owner {
+			res[n-1].EndLine = i + 1
+			continue
+		}
+		res = append(res, models.BlameRange{StartLine: i + 1, EndLine: i + 1, Revision: owner})
+	}
+	return res, approximate
+}
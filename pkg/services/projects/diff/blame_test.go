@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+func TestBlame(t *testing.T) {
+	r1 := models.RevisionRef{Number: 1, CreatedAt: 100}
+	r2 := models.RevisionRef{Number: 2, CreatedAt: 200}
+	r3 := models.RevisionRef{Number: 3, CreatedAt: 300}
+	current := models.RevisionRef{}
+	tests := []struct {
+		name      string
+		revisions []models.FileRevision
+		content   string
+		want      []models.BlameRange
+	}{
+		{
+			name:    "no revisions",
+			content: "a\nb\n",
+			want:    []models.BlameRange{{StartLine: 1, EndLine: 2, Revision: current}},
+		},
+		{
+			name:      "empty file",
+			revisions: []models.FileRevision{{RevisionRef: r1}},
+			want:      []models.BlameRange{},
+		},
+		{
+			name:      "unchanged since the first revision",
+			revisions: []models.FileRevision{{RevisionRef: r1, Content: "a\nb\n"}},
+			content:   "a\nb\n",
+			want:      []models.BlameRange{{StartLine: 1, EndLine: 2, Revision: r1}},
+		},
+		{
+			name: "insert, edit and delete",
+			revisions: []models.FileRevision{
+				{RevisionRef: r1, Content: "a\nb\nc\nd\n"},
+				{RevisionRef: r2, Content: "a\nx\nb\nc\nd\n"},
+				{RevisionRef: r3, Content: "a\nx\nB\nc\nd\n"},
+			},
+			content: "a\nx\nB\nd\n",
+			want: []models.BlameRange{
+				{StartLine: 1, EndLine: 1, Revision: r1},
+				{StartLine: 2, EndLine: 2, Revision: r2},
+				{StartLine: 3, EndLine: 3, Revision: r3},
+				{StartLine: 4, EndLine: 4, Revision: r1},
+			},
+		},
+		{
+			name:      "uncommitted change",
+			revisions: []models.FileRevision{{RevisionRef: r1, Content: "a\nb\n"}},
+			content:   "a\nc\n",
+			want: []models.BlameRange{
+				{StartLine: 1, EndLine: 1, Revision: r1},
+				{StartLine: 2, EndLine: 2, Revision: current},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, approximate := Blame(tt.revisions, tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Blame() = %+v, want %+v", got, tt.want)
+			}
+			if approximate {
+				t.Errorf("Blame() approximate = true, want false")
+			}
+		})
+	}
+}
+
+func TestBlameApproximate(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i <= maxEdits; i++ {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+	revisions := []models.FileRevision{{RevisionRef: models.RevisionRef{Number: 1}, Content: a.String()}}
+	if _, approximate := Blame(revisions, b.String()); !approximate {
+		t.Errorf("Blame() approximate = false, want true past maxEdits")
+	}
+}
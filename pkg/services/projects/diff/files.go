@@ -0,0 +1,216 @@
+// Package diff compares the code files of a project between two revisions,
+// line by line, and formats the changes as unified diffs.
+package diff
+
+import (
+	"sort"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// RenameSimilarity is the minimum similarity for a removed file and an added
+// one to be reported as a rename.
+const RenameSimilarity = 0.5
+
+// Pair is a file of the old set paired with a file of the new one, by their
+// index in each set. From is -1 for an added file, and To for a removed one.
+type Pair struct {
+	From int
+	To   int
+}
+
+// Files compares two sets of code files, with up to context unchanged lines
+// around each change. Files are paired by PairFiles and PairRenames.
+// Unchanged files are left out.
+func Files(from, to models.CodeFiles, context int) []models.FileDiff {
+	var res []models.FileDiff
+	for _, p := range PairRenames(from, to, PairFiles(from, to)) {
+		switch {
+		case p.From == -1:
+			res = append(res, compare(models.CodeFile{}, to[p.To], context))
+		case p.To == -1:
+			res = append(res, compare(from[p.From], models.CodeFile{}, context))
+		default:
+			if old, cur := from[p.From], to[p.To]; old.Name != cur.Name || old.Content != cur.Content {
+				res = append(res, compare(old, cur, context))
+			}
+		}
+	}
+	return res
+}
+
+// PairFiles pairs two sets of code files by id and, failing that, by name,
+// since replacing all the files of a project gives them new ids. The pairs
+// follow the new files, and the removed files come last.
+func PairFiles(from, to models.CodeFiles) []Pair {
+	pairs := make([]int, len(to))
+	paired := make([]bool, len(from))
+	fromById := make(map[int]int)
+	for i, f := range from {
+		if f.Id != 0 {
+			fromById[f.Id] = i
+		}
+	}
+	for i, f := range to {
+		pairs[i] = -1
+		if j, ok := fromById[f.Id]; ok && f.Id != 0 {
+			pairs[i], paired[j] = j, true
+		}
+	}
+	fromByName := make(map[string]int)
+	for i, f := range from {
+		if _, ok := fromByName[f.Name]; !ok && !paired[i] {
+			fromByName[f.Name] = i
+		}
+	}
+	for i, f := range to {
+		if j, ok := fromByName[f.Name]; ok && pairs[i] == -1 && !paired[j] {
+			pairs[i], paired[j] = j, true
+		}
+	}
+	return makePairs(pairs, paired)
+}
+
+// PairRenames pairs the added and removed files of a pairing by the
+// similarity of their contents, most similar first, and reports them as
+// renames. Only the contents of the added and removed files are read.
+func PairRenames(from, to models.CodeFiles, pairs []Pair) []Pair {
+	toFrom := make([]int, len(to))
+	for i := range toFrom {
+		toFrom[i] = -1
+	}
+	paired := make([]bool, len(from))
+	for _, p := range pairs {
+		if p.To != -1 {
+			toFrom[p.To] = p.From
+		}
+		if p.From != -1 && p.To != -1 {
+			paired[p.From] = true
+		}
+	}
+
+	type candidate struct {
+		from, to   int
+		similarity float64
+	}
+	var added, removed []int
+	for i := range to {
+		if toFrom[i] == -1 {
+			added = append(added, i)
+		}
+	}
+	for j := range from {
+		if !paired[j] {
+			removed = append(removed, j)
+		}
+	}
+	if len(added) == 0 || len(removed) == 0 {
+		return pairs
+	}
+	removedTexts := make([]renameText, len(removed))
+	for k, j := range removed {
+		removedTexts[k] = newRenameText(from[j].Content)
+	}
+	var candidates []candidate
+	for _, i := range added {
+		b := newRenameText(to[i].Content)
+		for k, j := range removed {
+			a := removedTexts[k]
+			if a.maxSimilarity(b) < RenameSimilarity {
+				continue
+			}
+			if s := similarity(script(a.keys, b.keys), a.keys, b.keys); s >= RenameSimilarity {
+				candidates = append(candidates, candidate{j, i, s})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+	for _, c := range candidates {
+		if toFrom[c.to] == -1 && !paired[c.from] {
+			toFrom[c.to], paired[c.from] = c.from, true
+		}
+	}
+	return makePairs(toFrom, paired)
+}
+
+// makePairs lists the pairs of the new files, given the old file each one is
+// paired with, followed by the old files left unpaired.
+func makePairs(toFrom []int, paired []bool) []Pair {
+	res := make([]Pair, 0, len(toFrom)+len(paired))
+	for i, j := range toFrom {
+		res = append(res, Pair{From: j, To: i})
+	}
+	for j, ok := range paired {
+		if !ok {
+			res = append(res, Pair{From: j, To: -1})
+		}
+	}
+	return res
+}
+
+// renameText is the content of a rename candidate, along with how many times
+// each of its lines appears.
+type renameText struct {
+	keys   []string
+	counts map[string]int
+}
+
+func newRenameText(content string) renameText {
+	t := renameText{keys: newText(content).keys(), counts: make(map[string]int)}
+	for _, k := range t.keys {
+		t.counts[k]++
+	}
+	return t
+}
+
+// maxSimilarity bounds the similarity of two texts by the lines they have in
+// common, regardless of their order. It rules out most rename candidates
+// without diffing them.
+func (t renameText) maxSimilarity(other renameText) float64 {
+	n, m := len(t.keys), len(other.keys)
+	if n+m > 0 && float64(2*min(n, m))/float64(n+m) < RenameSimilarity {
+		return 0
+	}
+	common := 0
+	for k, c := range t.counts {
+		common += min(c, other.counts[k])
+	}
+	return ratio(common, n, m)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// compare diffs two versions of a code file. An empty old name stands for an
+// added file, and an empty new name for a removed one.
+func compare(old, cur models.CodeFile, context int) models.FileDiff {
+	a, b := newText(old.Content), newText(cur.Content)
+	edits := script(a.keys(), b.keys())
+	res := models.FileDiff{
+		OldName: old.Name,
+		NewName: cur.Name,
+		Hunks:   hunks(edits, a, b, context),
+	}
+	switch {
+	case old.Name == "":
+		res.Status = models.DiffAdded
+	case cur.Name == "":
+		res.Status = models.DiffRemoved
+	case old.Name != cur.Name:
+		res.Status = models.DiffRenamed
+		res.Similarity = similarity(edits, a.lines, b.lines)
+	default:
+		res.Status = models.DiffModified
+		res.Similarity = similarity(edits, a.lines, b.lines)
+	}
+	if res.Hunks == nil {
+		res.Hunks = []models.DiffHunk{}
+	}
+	return res
+}
@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+func TestPairRenames(t *testing.T) {
+	long := "one\ntwo\nthree\nfour\n"
+	tests := []struct {
+		name     string
+		from, to models.CodeFiles
+		want     []Pair
+	}{
+		{
+			name: "paired by id",
+			from: models.CodeFiles{{Id: 1, Name: "a.go", Content: long}},
+			to:   models.CodeFiles{{Id: 1, Name: "b.go", Content: "other\n"}},
+			want: []Pair{{From: 0, To: 0}},
+		},
+		{
+			name: "paired by name when ids changed",
+			from: models.CodeFiles{{Id: 1, Name: "a.go", Content: long}},
+			to:   models.CodeFiles{{Id: 2, Name: "a.go", Content: "other\n"}},
+			want: []Pair{{From: 0, To: 0}},
+		},
+		{
+			name: "similar contents paired as a rename",
+			from: models.CodeFiles{{Id: 1, Name: "a.go", Content: long}},
+			to:   models.CodeFiles{{Id: 2, Name: "b.go", Content: strings.Replace(long, "four", "five", 1)}},
+			want: []Pair{{From: 0, To: 0}},
+		},
+		{
+			name: "different contents left added and removed",
+			from: models.CodeFiles{{Id: 1, Name: "a.go", Content: long}},
+			to:   models.CodeFiles{{Id: 2, Name: "b.go", Content: "x\ny\nz\n"}},
+			want: []Pair{{From: -1, To: 0}, {From: 0, To: -1}},
+		},
+		{
+			name: "most similar file wins",
+			from: models.CodeFiles{
+				{Id: 1, Name: "a.go", Content: "one\ntwo\nx\ny\n"},
+				{Id: 2, Name: "b.go", Content: long},
+			},
+			to:   models.CodeFiles{{Id: 3, Name: "c.go", Content: long}},
+			want: []Pair{{From: 1, To: 0}, {From: 0, To: -1}},
+		},
+		{
+			name: "much shorter file ruled out by size",
+			from: models.CodeFiles{{Id: 1, Name: "a.go", Content: long}},
+			to:   models.CodeFiles{{Id: 2, Name: "b.go", Content: "one\n"}},
+			want: []Pair{{From: -1, To: 0}, {From: 0, To: -1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PairRenames(tt.from, tt.to, PairFiles(tt.from, tt.to))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PairRenames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilesStatus(t *testing.T) {
+	from := models.CodeFiles{
+		{Id: 1, Name: "kept.go", Content: "a\n"},
+		{Id: 2, Name: "old.go", Content: "one\ntwo\nthree\n"},
+		{Id: 3, Name: "gone.go", Content: "x\n"},
+	}
+	to := models.CodeFiles{
+		{Id: 1, Name: "kept.go", Content: "a"},
+		{Id: 4, Name: "new.go", Content: "one\ntwo\nthree\n"},
+		{Id: 5, Name: "added.go", Content: "y\n"},
+	}
+	want := []models.DiffStatus{models.DiffModified, models.DiffRenamed, models.DiffAdded, models.DiffRemoved}
+	var got []models.DiffStatus
+	for _, f := range Files(from, to, 3) {
+		got = append(got, f.Status)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() statuses = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+package diff
+
+import (
+	"strings"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// maxEdits bounds the work spent on two very different texts. Past it, the
+// differing lines are reported as removed and then added in full, and
+// boundedScript tells the script is not the shortest one.
+const maxEdits = 1000
+
+// edit is an operation of the script turning the old lines into the new ones.
+// Both indexes are kept for every operation: for insertions, the old index is
+// the line they go before, and likewise for deletions.
+type edit struct {
+	op     models.DiffOp
+	oldIdx int
+	newIdx int
+}
+
+// splitLines splits a text into lines, leaving out the empty line after a
+// trailing newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// text is the content of a code file split into lines. NoNewline tells whether
+// its last line lacks a trailing newline.
+type text struct {
+	lines     []string
+	noNewline bool
+}
+
+func newText(content string) text {
+	return text{lines: splitLines(content), noNewline: content != "" && !strings.HasSuffix(content, "\n")}
+}
+
+// keys returns the lines as the edit script compares them. As in git, a last
+// line without a trailing newline differs from the same line with one, so
+// adding or removing the trailing newline alone is a change.
+func (t text) keys() []string {
+	if !t.noNewline {
+		return t.lines
+	}
+	keys := append([]string(nil), t.lines...)
+	keys[len(keys)-1] += "\x00"
+	return keys
+}
+
+// lastLine tells whether a line is the last one of the text without a
+// trailing newline.
+func (t text) lastLine(idx int) bool {
+	return t.noNewline && idx == len(t.lines)-1
+}
+
+// script returns the shortest edit script turning a into b, that is, the one
+// keeping their longest common subsequence of lines.
+func script(a, b []string) []edit {
+	edits, _ := boundedScript(a, b)
+	return edits
+}
+
+// boundedScript returns the edit script turning a into b, and whether it is
+// the shortest one. It is not when the texts differ by more than maxEdits.
+func boundedScript(a, b []string) ([]edit, bool) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	res := make([]edit, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		res = append(res, edit{models.DiffEqual, i, i})
+	}
+	middle, shortest := middleScript(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)
+	res = append(res, middle...)
+	for i := suffix; i > 0; i-- {
+		res = append(res, edit{models.DiffEqual, len(a) - i, len(b) - i})
+	}
+	return res, shortest
+}
+
+// middleScript runs Myers' algorithm on the lines left between the common
+// prefix and suffix, which start at the offset line of both texts. Past
+// maxEdits, it replaces all the lines and tells the script is not the shortest.
+func middleScript(a, b []string, offset int) ([]edit, bool) {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil, true
+	}
+	maxD := n + m
+	if maxD > maxEdits {
+		maxD = maxEdits
+	}
+
+	// v holds the furthest x reached on each diagonal k = x - y, at v[k+off].
+	off := maxD + 1
+	v := make([]int, 2*off+1)
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m, offset), true
+			}
+		}
+	}
+	return replaceAll(n, m, offset), false
+}
+
+// backtrack walks the trace of Myers' algorithm back from the end of both
+// texts, where trace[d] holds the diagonals reached before the step d.
+func backtrack(trace [][]int, n, m, offset int) []edit {
+	var rev []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, edit{models.DiffEqual, offset + x, offset + y})
+		}
+		if d > 0 {
+			if x == prevX {
+				rev = append(rev, edit{models.DiffInsert, offset + x, offset + prevY})
+			} else {
+				rev = append(rev, edit{models.DiffDelete, offset + prevX, offset + prevY})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	res := make([]edit, len(rev))
+	for i, e := range rev {
+		res[len(rev)-1-i] = e
+	}
+	return res
+}
+
+func replaceAll(n, m, offset int) []edit {
+	res := make([]edit, 0, n+m)
+	for i := 0; i < n; i++ {
+		res = append(res, edit{models.DiffDelete, offset + i, offset})
+	}
+	for j := 0; j < m; j++ {
+		res = append(res, edit{models.DiffInsert, offset + n, offset + j})
+	}
+	return res
+}
+
+// similarity returns the ratio of lines kept by an edit script, over the lines
+// of both texts.
+func similarity(edits []edit, a, b []string) float64 {
+	equal := 0
+	for _, e := range edits {
+		if e.op == models.DiffEqual {
+			equal++
+		}
+	}
+	return ratio(equal, len(a), len(b))
+}
+
+// ratio returns the ratio of equal lines over the lines of both texts.
+func ratio(equal, n, m int) float64 {
+	if n+m == 0 {
+		return 1
+	}
+	return float64(2*equal) / float64(n+m)
+}
+
+// hunks groups the changes of an edit script into hunks, with up to context
+// unchanged lines around them. Changes closer than twice the context share
+// the same hunk.
+func hunks(edits []edit, a, b text, context int) []models.DiffHunk {
+	var res []models.DiffHunk
+	for i := 0; i < len(edits); {
+		if edits[i].op == models.DiffEqual {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for {
+			for end < len(edits) && edits[end].op != models.DiffEqual {
+				end++
+			}
+			next := end
+			for next < len(edits) && edits[next].op == models.DiffEqual {
+				next++
+			}
+			if next < len(edits) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			if end+context < next {
+				next = end + context
+			}
+			end = next
+			break
+		}
+		res = append(res, hunk(edits[start:end], a, b))
+		i = end
+	}
+	return res
+}
+
+func hunk(edits []edit, a, b text) models.DiffHunk {
+	h := models.DiffHunk{
+		OldStart: edits[0].oldIdx + 1,
+		NewStart: edits[0].newIdx + 1,
+		Lines:    make([]models.DiffLine, len(edits)),
+	}
+	for i, e := range edits {
+		switch e.op {
+		case models.DiffEqual:
+			h.OldLines++
+			h.NewLines++
+			h.Lines[i] = models.DiffLine{Op: e.op, Text: a.lines[e.oldIdx], NoNewline: a.lastLine(e.oldIdx)}
+		case models.DiffDelete:
+			h.OldLines++
+			h.Lines[i] = models.DiffLine{Op: e.op, Text: a.lines[e.oldIdx], NoNewline: a.lastLine(e.oldIdx)}
+		case models.DiffInsert:
+			h.NewLines++
+			h.Lines[i] = models.DiffLine{Op: e.op, Text: b.lines[e.newIdx], NoNewline: b.lastLine(e.newIdx)}
+		}
+	}
+	if h.OldLines == 0 {
+		h.OldStart--
+	}
+	if h.NewLines == 0 {
+		h.NewStart--
+	}
+	return h
+}
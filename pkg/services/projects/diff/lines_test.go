@@ -0,0 +1,132 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"both empty", "", "", ""},
+		{"added to empty", "", "a\nb\n", "++"},
+		{"removed all", "a\nb\n", "", "--"},
+		{"unchanged", "a\nb\nc\n", "a\nb\nc\n", "==="},
+		{"insert in the middle", "a\nc\n", "a\nb\nc\n", "=+="},
+		{"delete in the middle", "a\nb\nc\n", "a\nc\n", "=-="},
+		{"replace a line", "a\nb\nc\n", "a\nx\nc\n", "=-+="},
+		{"missing trailing newline added", "a\nb", "a\nb\n", "=-+"},
+		{"trailing newline removed", "a\nb\n", "a\nb", "=-+"},
+		{"both without trailing newline", "a\nb", "a\nb", "=="},
+	}
+	ops := map[models.DiffOp]byte{models.DiffEqual: '=', models.DiffDelete: '-', models.DiffInsert: '+'}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []byte
+			for _, e := range script(newText(tt.a).keys(), newText(tt.b).keys()) {
+				got = append(got, ops[e.op])
+			}
+			if string(got) != tt.want {
+				t.Errorf("script(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    []models.DiffHunk
+	}{
+		{
+			name: "unchanged",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+		},
+		{
+			name: "added to empty",
+			a:    "",
+			b:    "a\n",
+			want: []models.DiffHunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []models.DiffLine{
+				{Op: models.DiffInsert, Text: "a"},
+			}}},
+		},
+		{
+			name: "removed all",
+			a:    "a\nb\n",
+			b:    "",
+			want: []models.DiffHunk{{OldStart: 1, OldLines: 2, NewStart: 0, NewLines: 0, Lines: []models.DiffLine{
+				{Op: models.DiffDelete, Text: "a"},
+				{Op: models.DiffDelete, Text: "b"},
+			}}},
+		},
+		{
+			name:    "context around a change",
+			a:       "1\n2\n3\n4\n5\n",
+			b:       "1\n2\nx\n4\n5\n",
+			context: 1,
+			want: []models.DiffHunk{{OldStart: 2, OldLines: 3, NewStart: 2, NewLines: 3, Lines: []models.DiffLine{
+				{Op: models.DiffEqual, Text: "2"},
+				{Op: models.DiffDelete, Text: "3"},
+				{Op: models.DiffInsert, Text: "x"},
+				{Op: models.DiffEqual, Text: "4"},
+			}}},
+		},
+		{
+			name:    "distant changes in separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n",
+			b:       "x\n2\n3\n4\n5\ny\n",
+			context: 1,
+			want: []models.DiffHunk{
+				{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2, Lines: []models.DiffLine{
+					{Op: models.DiffDelete, Text: "1"},
+					{Op: models.DiffInsert, Text: "x"},
+					{Op: models.DiffEqual, Text: "2"},
+				}},
+				{OldStart: 5, OldLines: 2, NewStart: 5, NewLines: 2, Lines: []models.DiffLine{
+					{Op: models.DiffEqual, Text: "5"},
+					{Op: models.DiffDelete, Text: "6"},
+					{Op: models.DiffInsert, Text: "y"},
+				}},
+			},
+		},
+		{
+			name:    "trailing newline added",
+			a:       "a\nb",
+			b:       "a\nb\n",
+			context: 3,
+			want: []models.DiffHunk{{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2, Lines: []models.DiffLine{
+				{Op: models.DiffEqual, Text: "a"},
+				{Op: models.DiffDelete, Text: "b", NoNewline: true},
+				{Op: models.DiffInsert, Text: "b"},
+			}}},
+		},
+		{
+			name:    "change before a last line without newline",
+			a:       "a\nb",
+			b:       "x\nb",
+			context: 3,
+			want: []models.DiffHunk{{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2, Lines: []models.DiffLine{
+				{Op: models.DiffDelete, Text: "a"},
+				{Op: models.DiffInsert, Text: "x"},
+				{Op: models.DiffEqual, Text: "b", NoNewline: true},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := newText(tt.a), newText(tt.b)
+			got := hunks(script(a.keys(), b.keys()), a, b, tt.context)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hunks(%q, %q) = %+v, want %+v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// linePrefixes are the markers of each kind of line in the unified format.
+var linePrefixes = map[models.DiffOp]byte{
+	models.DiffEqual:  ' ',
+	models.DiffDelete: '-',
+	models.DiffInsert: '+',
+}
+
+// fileMode is the mode of the code files in the git extended headers.
+const fileMode = "100644"
+
+// Unified writes the changes of the code files in the unified format, with
+// the git extended headers for the added, removed and renamed files, so the
+// output applies as a patch.
+func Unified(w io.Writer, files []models.FileDiff) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range files {
+		oldName, newName := f.OldName, f.NewName
+		if oldName == "" {
+			oldName = newName
+		}
+		if newName == "" {
+			newName = oldName
+		}
+		fmt.Fprintf(bw, "diff --git a/%s b/%s\n", oldName, newName)
+		switch f.Status {
+		case models.DiffAdded:
+			fmt.Fprintf(bw, "new file mode %s\n", fileMode)
+		case models.DiffRemoved:
+			fmt.Fprintf(bw, "deleted file mode %s\n", fileMode)
+		case models.DiffRenamed:
+			fmt.Fprintf(bw, "similarity index %d%%\n", int(math.Floor(f.Similarity*100)))
+			fmt.Fprintf(bw, "rename from %s\nrename to %s\n", f.OldName, f.NewName)
+		}
+		if len(f.Hunks) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "--- %s\n+++ %s\n", fileHeader("a/", f.OldName), fileHeader("b/", f.NewName))
+		for _, h := range f.Hunks {
+			fmt.Fprintf(bw, "@@ -%s +%s @@\n", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+			for _, l := range h.Lines {
+				bw.WriteByte(linePrefixes[l.Op])
+				bw.WriteString(l.Text)
+				bw.WriteByte('\n')
+				if l.NoNewline {
+					bw.WriteString("\\ No newline at end of file\n")
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func fileHeader(prefix, name string) string {
+	if name == "" {
+		return "/dev/null"
+	}
+	return prefix + name
+}
+
+// hunkRange prints the range of lines of a hunk, leaving out the count of the
+// ranges holding a single line.
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
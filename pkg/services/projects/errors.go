@@ -7,10 +7,17 @@ import (
 )
 
 var (
-	ErrProjectTimeout           = NewError("request timeout")
-	ErrProjectNotFound          = NewError("requested project could not be found")
-	ErrAddProjectDuplicatedName = NewError("duplicated name")
-	ErrDecodeBody               = NewError("failed to decode body")
+	ErrProjectTimeout               = NewError("request timeout")
+	ErrProjectNotFound              = NewError("requested project could not be found")
+	ErrAddProjectDuplicatedName     = NewError("duplicated name")
+	ErrDecodeBody                   = NewError("failed to decode body")
+	ErrCodeFileNotFound             = NewError("requested code file could not be found")
+	ErrRevisionNotFound             = NewError("requested revision could not be found")
+	ErrTooManyCodeFiles             = NewError("total of code files exceeded the maximum limit")
+	ErrSynonymNotFound              = NewError("requested synonym could not be found")
+	ErrAddSynonymDuplicatedTerm     = NewError("duplicated synonym term")
+	ErrSavedSearchNotFound          = NewError("requested saved search could not be found")
+	ErrAddSavedSearchDuplicatedName = NewError("duplicated saved search name")
 )
 
 type outboundError struct {
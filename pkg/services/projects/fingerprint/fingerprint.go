@@ -0,0 +1,77 @@
+package fingerprint
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ShingleTokens is the number of tokens hashed together in each shingle.
+	ShingleTokens = 5
+	// Window is the number of consecutive shingles a fingerprint is picked
+	// from. Any match at least Window+ShingleTokens-1 tokens long shares at
+	// least one fingerprint.
+	Window = 4
+)
+
+// tokenRe splits code into identifiers, numbers and single punctuation characters.
+var tokenRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*|[0-9]+|\S`)
+
+// Fingerprint is the hash of a shingle of tokens, along with the line it starts at.
+type Fingerprint struct {
+	Hash int64
+	Line int
+}
+
+type token struct {
+	text string
+	line int
+}
+
+// Winnow computes the fingerprints of a code file by winnowing the hashes of
+// its shingles: the minimum hash of every window is kept. Tokens are lower
+// cased, so whitespace, layout and case changes leave the fingerprints as they
+// were.
+func Winnow(content string) []Fingerprint {
+	var tokens []token
+	for i, line := range strings.Split(content, "\n") {
+		for _, t := range tokenRe.FindAllString(line, -1) {
+			tokens = append(tokens, token{strings.ToLower(t), i + 1})
+		}
+	}
+	if len(tokens) < ShingleTokens {
+		return nil
+	}
+
+	shingles := make([]Fingerprint, len(tokens)-ShingleTokens+1)
+	for i := range shingles {
+		h := fnv.New64a()
+		for _, t := range tokens[i : i+ShingleTokens] {
+			h.Write([]byte(t.text))
+			h.Write([]byte{0})
+		}
+		shingles[i] = Fingerprint{Hash: int64(h.Sum64()), Line: tokens[i].line}
+	}
+
+	window := Window
+	if len(shingles) < window {
+		window = len(shingles)
+	}
+	var res []Fingerprint
+	last := -1
+	for start := 0; start+window <= len(shingles); start++ {
+		pick := start
+		for i := start + 1; i < start+window; i++ {
+			// The rightmost minimum is kept, so runs of equal hashes are not recorded twice.
+			if shingles[i].Hash <= shingles[pick].Hash {
+				pick = i
+			}
+		}
+		if pick != last {
+			res = append(res, shingles[pick])
+			last = pick
+		}
+	}
+	return res
+}
@@ -0,0 +1,97 @@
+package language
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+var (
+	// extensions maps the file extensions to their languages.
+	extensions = make(map[string]string)
+
+	// interpreters maps the interpreters found in shebang lines to their languages.
+	interpreters = map[string]string{
+		"bash":    "shell",
+		"sh":      "shell",
+		"zsh":     "shell",
+		"node":    "javascript",
+		"deno":    "typescript",
+		"python":  "python",
+		"python3": "python",
+		"ruby":    "ruby",
+		"php":     "php",
+	}
+
+	// heuristics are tried in order on the content of the files that have
+	// neither a known extension nor a shebang line.
+	heuristics = []struct {
+		lang string
+		re   *regexp.Regexp
+	}{
+		{"go", regexp.MustCompile(`(?m)^package \w+\s*$|^func (\(\w+ \*?\w+\) )?\w+\(|:= `)},
+		{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+|let mut |impl\b.*\{`)},
+		{"cpp", regexp.MustCompile(`(?m)^#include\s*<(iostream|vector|string|map)>|std::|^\s*namespace \w+`)},
+		{"c", regexp.MustCompile(`(?m)^#include\s*[<"]|^int main\(`)},
+		{"java", regexp.MustCompile(`(?m)^import java\.|public (static )?(class|void) `)},
+		{"csharp", regexp.MustCompile(`(?m)^using System|namespace \w+(\.\w+)*\s*[{;]`)},
+		{"python", regexp.MustCompile(`(?m)^\s*def \w+\(.*\)\s*(->.*)?:\s*$|^\s*(from \w+(\.\w+)* )?import \w+\s*$|^if __name__ ==`)},
+		{"php", regexp.MustCompile(`<\?php`)},
+		{"html", regexp.MustCompile(`(?i)<!doctype html|<html[\s>]`)},
+		{"sql", regexp.MustCompile(`(?i)^\s*(SELECT\s.+\sFROM|CREATE TABLE|INSERT INTO|UPDATE\s+\w+\s+SET)\s`)},
+		{"typescript", regexp.MustCompile(`(?m)^\s*(export )?(interface|type) \w+\s*(=|\{)|:\s*(string|number|boolean)\b`)},
+		{"javascript", regexp.MustCompile(`(?m)^\s*(const|let|var) \w+\s*=|function\s*\w*\(|=>|require\(`)},
+		{"shell", regexp.MustCompile(`(?m)^\s*(echo|export|fi|done)\b`)},
+	}
+)
+
+func init() {
+	for lang, exts := range models.LanguageExtensions {
+		for _, ext := range exts {
+			extensions[ext] = lang
+		}
+	}
+}
+
+// Detect guesses the language of a code file from its extension, its shebang
+// line or, as a last resort, its content. It returns an empty string when the
+// language is unknown.
+func Detect(name, content string) string {
+	if lang, ok := extensions[strings.ToLower(path.Ext(name))]; ok {
+		return lang
+	}
+	if lang := fromShebang(content); lang != "" {
+		return lang
+	}
+	trimmed := strings.TrimSpace(content)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+	for _, h := range heuristics {
+		if h.re.MatchString(content) {
+			return h.lang
+		}
+	}
+	return ""
+}
+
+// fromShebang reads the language from the interpreter of a shebang line, as in
+// "#!/bin/bash" or "#!/usr/bin/env python3".
+func fromShebang(content string) string {
+	if !strings.HasPrefix(content, "#!") {
+		return ""
+	}
+	line := strings.SplitN(content[2:], "\n", 2)[0]
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+	return interpreters[interpreter]
+}
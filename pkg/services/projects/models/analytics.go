@@ -0,0 +1,155 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"lastimplementation.com/internal/validate"
+)
+
+const (
+	defaultAnalyticsLimit  = 20
+	defaultAnalyticsWindow = 24 * time.Hour
+	maximumAnalyticsWindow = 90 * 24 * time.Hour
+)
+
+// SearchKind is the kind of search recorded in the search log.
+type SearchKind string
+
+const (
+	SearchKindProjects SearchKind = "projects"
+	SearchKindCode     SearchKind = "code"
+)
+
+var spacesRe = regexp.MustCompile(`\s+`)
+
+// SearchOutcome is how a search recorded in the search log ended.
+type SearchOutcome string
+
+const (
+	SearchOutcomeOK      SearchOutcome = "ok"
+	SearchOutcomeError   SearchOutcome = "error"
+	SearchOutcomeTimeout SearchOutcome = "timeout"
+)
+
+// SearchLogEntry is a search invocation recorded in the search log.
+type SearchLogEntry struct {
+	Kind    SearchKind
+	Query   string
+	Filters string
+	Results int
+	Latency time.Duration
+	Outcome SearchOutcome
+}
+
+// NormalizeQuery lower cases a query and collapses its whitespace, so the same
+// query is always logged the same way.
+func NormalizeQuery(query string) string {
+	return strings.TrimSpace(spacesRe.ReplaceAllString(strings.ToLower(query), " "))
+}
+
+// LogFilters returns the parameters of a projects search, other than its query
+// and page, as an ordered query string.
+func (qp SearchQP) LogFilters() string {
+	values := url.Values{}
+	var tags []string
+	for _, tag := range qp.Tags {
+		tags = append(tags, string(tag))
+	}
+	for _, tag := range qp.ExcludedTags {
+		tags = append(tags, "-"+string(tag))
+	}
+	if len(tags) > 0 {
+		values.Set("tags", strings.Join(tags, ","))
+		values.Set("tagMode", string(qp.TagMode))
+	}
+	values.Set("sort", string(qp.Sort))
+	values.Set("order", string(qp.Order))
+	for param, t := range map[string]*time.Time{
+		"updatedFrom": qp.UpdatedFrom,
+		"updatedTo":   qp.UpdatedTo,
+		"createdFrom": qp.CreatedFrom,
+		"createdTo":   qp.CreatedTo,
+	} {
+		if t != nil {
+			values.Set(param, t.Format(time.RFC3339))
+		}
+	}
+	return values.Encode()
+}
+
+// QueryStat aggregates the searches of a query.
+type QueryStat struct {
+	Kind           SearchKind `json:"kind"`
+	Query          string     `json:"query"`
+	Filters        string     `json:"filters,omitempty"`
+	Count          int        `json:"count"`
+	AvgResults     float64    `json:"avgResults"`
+	LastSearchedAt int64      `json:"lastSearchedAt"`
+}
+
+type QueryStats []QueryStat
+
+func (qs *QueryStats) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(qs)
+}
+
+// LatencyStats holds the latency percentiles of the searches, in milliseconds.
+type LatencyStats struct {
+	Count    int     `json:"count"`
+	Errors   int     `json:"errors"`
+	Timeouts int     `json:"timeouts"`
+	P50      float64 `json:"p50"`
+	P90      float64 `json:"p90"`
+	P95      float64 `json:"p95"`
+	P99      float64 `json:"p99"`
+	Max      float64 `json:"max"`
+}
+
+func (ls *LatencyStats) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(ls)
+}
+
+type AnalyticsQP struct {
+	Window time.Duration
+	Kind   SearchKind `validate:"omitempty,oneof=projects code"`
+	Limit  int        `validate:"min=1,max=100"`
+}
+
+// NewAnalyticsQP reads the analytics query. The window is a duration, as in
+// "24h", counting back from now.
+func NewAnalyticsQP(values url.Values) (AnalyticsQP, error) {
+	var res AnalyticsQP
+	if limit := values.Get("limit"); limit != "" {
+		limitNum, err := strconv.Atoi(limit)
+		if err != nil {
+			return res, err
+		}
+		res.Limit = limitNum
+	} else {
+		res.Limit = defaultAnalyticsLimit
+	}
+	if window := values.Get("window"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			return res, fmt.Errorf("invalid window %q: expected a duration, such as 24h", window)
+		}
+		if d <= 0 || d > maximumAnalyticsWindow {
+			return res, fmt.Errorf("invalid window %q: must be positive and at most %s", window, maximumAnalyticsWindow)
+		}
+		res.Window = d
+	} else {
+		res.Window = defaultAnalyticsWindow
+	}
+	res.Kind = SearchKind(values.Get("kind"))
+	if err := validate.Get().Struct(res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
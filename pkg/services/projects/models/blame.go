@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// BlameRange is a run of consecutive lines of a code file last changed in the
+// same revision. Lines start at 1 and the end line is included.
+type BlameRange struct {
+	StartLine int         `json:"startLine"`
+	EndLine   int         `json:"endLine"`
+	Revision  RevisionRef `json:"revision"`
+}
+
+// Blame tells the revision that last changed each line of a code file. Lines
+// not recorded in any revision yet belong to the zero revision. Truncated
+// tells that older revisions were left out, so the lines of the oldest
+// revision may be older. Approximate tells that two revisions were too
+// different to diff line by line, so their differing lines were all taken as
+// changed.
+type Blame struct {
+	FileId      int          `json:"fileId"`
+	Name        string       `json:"name"`
+	Ranges      []BlameRange `json:"ranges"`
+	Truncated   bool         `json:"truncated"`
+	Approximate bool         `json:"approximate"`
+}
+
+func (b *Blame) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(b)
+}
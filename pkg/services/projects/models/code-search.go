@@ -0,0 +1,117 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"lastimplementation.com/internal/validate"
+)
+
+const (
+	defaultCodeSearchLimit = 200
+	// CodeSearchTimeout is the maximum time spent on a single code search.
+	CodeSearchTimeout = 3 * time.Second
+)
+
+type CodeSearchQP struct {
+	Pattern string `validate:"min=1,max=200"`
+	Regexp  *regexp.Regexp
+	History bool
+	Limit   int `validate:"min=1,max=1000"`
+}
+
+func NewCodeSearchQP(values url.Values) (CodeSearchQP, error) {
+	var res CodeSearchQP
+	if limit := values.Get("limit"); limit != "" {
+		limitNum, err := strconv.Atoi(limit)
+		if err != nil {
+			return res, err
+		}
+		res.Limit = limitNum
+	} else {
+		res.Limit = defaultCodeSearchLimit
+	}
+	if history := values.Get("history"); history != "" {
+		historyBool, err := strconv.ParseBool(history)
+		if err != nil {
+			return res, err
+		}
+		res.History = historyBool
+	}
+	res.Pattern = values.Get("re")
+	if err := validate.Get().Struct(res); err != nil {
+		return res, err
+	}
+	re, err := regexp.Compile(res.Pattern)
+	if err != nil {
+		return res, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	res.Regexp = re
+	return res, nil
+}
+
+// ProjectFile is a code file along with the project it belongs to.
+type ProjectFile struct {
+	ProjectId   int
+	ProjectName string
+	CodeFile
+}
+
+// ProjectFileRevision is a code file as it was recorded in a revision, along
+// with its project, the revision of the project following it, if any, and
+// the latest revision of the project.
+type ProjectFileRevision struct {
+	ProjectId      int
+	ProjectName    string
+	FileId         int
+	LatestRevision int
+	NextRevision   int
+	FileRevision
+}
+
+// CodeSearchFile holds the matches of a code search within a single file. It
+// is partial when the search timed out while going through the revisions of
+// the file, so some of its matches may be missing.
+type CodeSearchFile struct {
+	ProjectId   int               `json:"projectId"`
+	ProjectName string            `json:"projectName"`
+	FileId      int               `json:"fileId"`
+	FileName    string            `json:"fileName"`
+	Matches     []CodeSearchMatch `json:"matches"`
+	Partial     bool              `json:"partial,omitempty"`
+}
+
+// CodeSearchMatch is a line matched by a code search. When searching the
+// history, it also lists the revisions holding the line, and whether it was
+// deleted after the last of them, along with the revision that deleted it.
+type CodeSearchMatch struct {
+	Line      int         `json:"line"`
+	Text      string      `json:"text"`
+	Ranges    []Highlight `json:"ranges"`
+	Revisions []int       `json:"revisions,omitempty"`
+	Deleted   bool        `json:"deleted,omitempty"`
+	DeletedIn int         `json:"deletedIn,omitempty"`
+}
+
+// CodeSearchSummary describes how a code search ended.
+type CodeSearchSummary struct {
+	Files     int  `json:"files"`
+	Matches   int  `json:"matches"`
+	Truncated bool `json:"truncated"`
+	TimedOut  bool `json:"timedOut"`
+}
+
+// CodeSearchEvent is each of the entries streamed by a code search: the matched files, followed by the summary.
+type CodeSearchEvent struct {
+	File    *CodeSearchFile    `json:"file,omitempty"`
+	Summary *CodeSearchSummary `json:"summary,omitempty"`
+}
+
+func (e *CodeSearchEvent) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(e)
+}
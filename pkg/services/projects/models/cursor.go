@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursorTimeLayout is the layout of the date keys of the cursors, in UTC and
+// with the microseconds precision of the database.
+const cursorTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// Cursor points to the last item of a page in the projects list. It holds the
+// sort key and the id of the item, so the next page starts right after it.
+// Scope is a hash of the query and filters of the list, so the cursor is only
+// used with the list it came from.
+type Cursor struct {
+	Sort  SortField `json:"s"`
+	Order SortOrder `json:"o"`
+	Scope string    `json:"q"`
+	Key   string    `json:"k"`
+	Id    int       `json:"i"`
+}
+
+// Encode returns the opaque token of the cursor.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor decodes an opaque cursor token, checking its key holds a value
+// of the type of its sort field.
+func DecodeCursor(token string) (Cursor, error) {
+	var c Cursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, errInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.Id < 1 {
+		return c, errInvalidCursor
+	}
+	if _, err := c.SortValue(); err != nil {
+		return c, errInvalidCursor
+	}
+	return c, nil
+}
+
+// SortValue reads the key of the cursor as a value of the type of its sort
+// field: a string for names, a time for dates, a float for relevance and an
+// integer for file counts.
+func (c Cursor) SortValue() (interface{}, error) {
+	switch c.Sort {
+	case SortByName:
+		return c.Key, nil
+	case SortByUpdatedAt, SortByCreatedAt:
+		return time.Parse(cursorTimeLayout, c.Key)
+	case SortByRelevance:
+		return strconv.ParseFloat(c.Key, 64)
+	case SortByFileCount:
+		return strconv.ParseInt(c.Key, 10, 64)
+	}
+	return nil, fmt.Errorf("unsupported sort field %q", c.Sort)
+}
+
+// TimeCursorKey returns the cursor key of a date.
+func TimeCursorKey(t time.Time) string {
+	return t.UTC().Format(cursorTimeLayout)
+}
+
+// NextCursor returns the cursor of the page after the item with the given
+// sort key and id.
+func (qp SearchQP) NextCursor(key string, id int) Cursor {
+	return Cursor{Sort: qp.Sort, Order: qp.Order, Scope: qp.cursorScope(), Key: key, Id: id}
+}
+
+// cursorScope hashes the query and filters of the list.
+func (qp SearchQP) cursorScope() string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", qp.Query, joinTags(qp.Tags), joinTags(qp.ExcludedTags), qp.TagMode)
+	for _, t := range []*time.Time{qp.UpdatedFrom, qp.UpdatedTo, qp.CreatedFrom, qp.CreatedTo} {
+		if t != nil {
+			fmt.Fprintf(h, "\x00%d", t.UnixNano())
+		} else {
+			fmt.Fprint(h, "\x00-")
+		}
+	}
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+func joinTags(tags []TagType) string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = string(tag)
+	}
+	return strings.Join(names, ",")
+}
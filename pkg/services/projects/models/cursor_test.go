@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	updated := time.Date(2026, 1, 2, 15, 4, 5, 123456000, time.FixedZone("CET", 3600))
+	tests := []struct {
+		name   string
+		cursor Cursor
+		want   interface{}
+	}{
+		{"name", Cursor{Sort: SortByName, Order: SortAsc, Key: "parser", Id: 3}, "parser"},
+		{"updated at", Cursor{Sort: SortByUpdatedAt, Order: SortDesc, Key: TimeCursorKey(updated), Id: 3}, updated.UTC()},
+		{"created at", Cursor{Sort: SortByCreatedAt, Order: SortAsc, Key: TimeCursorKey(updated), Id: 3}, updated.UTC()},
+		{"relevance", Cursor{Sort: SortByRelevance, Order: SortDesc, Key: "0.25", Id: 3}, 0.25},
+		{"file count", Cursor{Sort: SortByFileCount, Order: SortDesc, Key: "12", Id: 3}, int64(12)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.cursor.Encode())
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if got != tt.cursor {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, tt.cursor)
+			}
+			value, err := got.SortValue()
+			if err != nil {
+				t.Fatalf("SortValue() error = %v", err)
+			}
+			if value != tt.want {
+				t.Errorf("SortValue() = %v, want %v", value, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "!!!"},
+		{"not json", raw("cursor")},
+		{"missing id", raw(`{"s":"name","o":"asc","k":"a"}`)},
+		{"unknown sort", raw(`{"s":"stars","o":"asc","k":"a","i":1}`)},
+		{"text key for a date", Cursor{Sort: SortByUpdatedAt, Order: SortDesc, Key: "parser", Id: 1}.Encode()},
+		{"text key for a relevance", Cursor{Sort: SortByRelevance, Order: SortDesc, Key: "high", Id: 1}.Encode()},
+		{"float key for a file count", Cursor{Sort: SortByFileCount, Order: SortDesc, Key: "1.5", Id: 1}.Encode()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.token); err != errInvalidCursor {
+				t.Errorf("DecodeCursor(%q) error = %v, want %v", tt.token, err, errInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestSearchQPCursor(t *testing.T) {
+	first, err := NewSearchQP(url.Values{"q": {"parser"}, "tags": {"LANGUAGE"}, "sort": {"name"}})
+	if err != nil {
+		t.Fatalf("NewSearchQP() error = %v", err)
+	}
+	token := first.NextCursor("parser", 3).Encode()
+
+	tests := []struct {
+		name   string
+		values url.Values
+		valid  bool
+	}{
+		{"same list", url.Values{"q": {"parser"}, "tags": {"LANGUAGE"}, "sort": {"name"}}, true},
+		{"other page size", url.Values{"q": {"parser"}, "tags": {"LANGUAGE"}, "sort": {"name"}, "limit": {"50"}}, true},
+		{"other query", url.Values{"q": {"lexer"}, "tags": {"LANGUAGE"}, "sort": {"name"}}, false},
+		{"other tags", url.Values{"q": {"parser"}, "tags": {"ARCHITECTURE"}, "sort": {"name"}}, false},
+		{"other tag mode", url.Values{"q": {"parser"}, "tags": {"LANGUAGE"}, "tagMode": {"any"}, "sort": {"name"}}, false},
+		{"other date range", url.Values{"q": {"parser"}, "tags": {"LANGUAGE"}, "sort": {"name"}, "updatedFrom": {"2026-01-02T00:00:00Z"}}, false},
+		{"other sort", url.Values{"q": {"parser"}, "tags": {"LANGUAGE"}, "sort": {"createdAt"}}, false},
+		{"other order", url.Values{"q": {"parser"}, "tags": {"LANGUAGE"}, "sort": {"name"}, "order": {"desc"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.values.Set("cursor", token)
+			qp, err := NewSearchQP(tt.values)
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("NewSearchQP() error = %v", err)
+				}
+				if qp.Cursor == nil || qp.Cursor.Key != "parser" || qp.Cursor.Id != 3 {
+					t.Errorf("NewSearchQP() cursor = %+v, want the parser cursor", qp.Cursor)
+				}
+			} else if err != errInvalidCursor {
+				t.Errorf("NewSearchQP() error = %v, want %v", err, errInvalidCursor)
+			}
+		})
+	}
+}
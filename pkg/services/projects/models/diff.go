@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"lastimplementation.com/internal/validate"
+)
+
+const defaultDiffContext = 3
+
+// DiffContentType is the media type of the diffs in the unified format.
+const DiffContentType = "text/x-diff"
+
+// DiffStatus tells how a code file changed between two revisions.
+type DiffStatus string
+
+const (
+	DiffAdded    DiffStatus = "added"
+	DiffRemoved  DiffStatus = "removed"
+	DiffModified DiffStatus = "modified"
+	DiffRenamed  DiffStatus = "renamed"
+)
+
+// DiffOp is the kind of a line of a diff hunk.
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffDelete DiffOp = "delete"
+	DiffInsert DiffOp = "insert"
+)
+
+// DiffLine is a line of a hunk. NoNewline marks the last line of a file
+// without a trailing newline.
+type DiffLine struct {
+	Op        DiffOp `json:"op"`
+	Text      string `json:"text"`
+	NoNewline bool   `json:"noNewline,omitempty"`
+}
+
+// DiffHunk is a run of changed lines, surrounded by some unchanged ones. The
+// lines start at 1, and the start of an empty range is the line before it.
+type DiffHunk struct {
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// FileDiff holds the changes of a code file. The old name is empty for the
+// added files and the new name is empty for the removed ones. Similarity is
+// the ratio of lines the old and new contents share.
+type FileDiff struct {
+	Status     DiffStatus `json:"status"`
+	OldName    string     `json:"oldName,omitempty"`
+	NewName    string     `json:"newName,omitempty"`
+	Similarity float64    `json:"similarity"`
+	Hunks      []DiffHunk `json:"hunks"`
+}
+
+// Diff holds the changed code files of a project between two revisions. A
+// missing target revision stands for the current code files.
+type Diff struct {
+	From  int        `json:"from"`
+	To    int        `json:"to,omitempty"`
+	Files []FileDiff `json:"files"`
+}
+
+func (d *Diff) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(d)
+}
+
+type DiffQP struct {
+	From    int `validate:"min=1"`
+	To      int `validate:"min=0"`
+	Context int `validate:"min=0,max=100"`
+	Unified bool
+}
+
+// NewDiffQP reads the revisions to compare. The unified format is requested
+// with "format=diff" or by accepting text/x-diff.
+func NewDiffQP(values url.Values, accept string) (DiffQP, error) {
+	var res DiffQP
+	if values.Get("from") == "" {
+		return res, errors.New("missing from revision")
+	}
+	from, err := strconv.Atoi(values.Get("from"))
+	if err != nil {
+		return res, err
+	}
+	res.From = from
+	if to := values.Get("to"); to != "" {
+		toNum, err := strconv.Atoi(to)
+		if err != nil {
+			return res, err
+		}
+		res.To = toNum
+	}
+	if context := values.Get("context"); context != "" {
+		contextNum, err := strconv.Atoi(context)
+		if err != nil {
+			return res, err
+		}
+		res.Context = contextNum
+	} else {
+		res.Context = defaultDiffContext
+	}
+	res.Unified = values.Get("format") == "diff" || strings.Contains(accept, DiffContentType)
+	if err := validate.Get().Struct(res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+
+	"lastimplementation.com/internal/validate"
+)
+
+const (
+	defaultDuplicatesLimit         = 20
+	defaultDuplicatesMinSimilarity = 0.5
+)
+
+// DuplicateFile is a code file of another project similar to the requested
+// one. Similarity is the share of fingerprints both files have in common.
+type DuplicateFile struct {
+	ProjectId   int     `json:"projectId"`
+	ProjectName string  `json:"projectName"`
+	FileId      int     `json:"fileId"`
+	FileName    string  `json:"fileName"`
+	Similarity  float64 `json:"similarity"`
+}
+
+type DuplicateFiles []DuplicateFile
+
+func (dfs *DuplicateFiles) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(dfs)
+}
+
+// DuplicatedSnippet is a piece of code found in several code files, along with where it was found.
+type DuplicatedSnippet struct {
+	Text        string              `json:"text"`
+	Files       int                 `json:"files"`
+	Projects    int                 `json:"projects"`
+	Occurrences []SnippetOccurrence `json:"occurrences"`
+}
+
+type SnippetOccurrence struct {
+	ProjectId int    `json:"projectId"`
+	FileId    int    `json:"fileId"`
+	FileName  string `json:"fileName"`
+	Line      int    `json:"line"`
+}
+
+type DuplicatedSnippets []DuplicatedSnippet
+
+func (dss *DuplicatedSnippets) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(dss)
+}
+
+type DuplicatesQP struct {
+	MinSimilarity float64 `validate:"min=0,max=1"`
+	Limit         int     `validate:"min=1,max=100"`
+}
+
+func NewDuplicatesQP(values url.Values) (DuplicatesQP, error) {
+	var res DuplicatesQP
+	if limit := values.Get("limit"); limit != "" {
+		limitNum, err := strconv.Atoi(limit)
+		if err != nil {
+			return res, err
+		}
+		res.Limit = limitNum
+	} else {
+		res.Limit = defaultDuplicatesLimit
+	}
+	if minSimilarity := values.Get("minSimilarity"); minSimilarity != "" {
+		minSimilarityNum, err := strconv.ParseFloat(minSimilarity, 64)
+		if err != nil {
+			return res, err
+		}
+		res.MinSimilarity = minSimilarityNum
+	} else {
+		res.MinSimilarity = defaultDuplicatesMinSimilarity
+	}
+	if err := validate.Get().Struct(res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import "strings"
+
+// LanguageExtensions maps the known languages to the file extensions written in them.
+var LanguageExtensions = map[string][]string{
+	"c":          {".c", ".h"},
+	"cpp":        {".cpp", ".cc", ".cxx", ".hpp", ".hh"},
+	"csharp":     {".cs"},
+	"css":        {".css", ".scss", ".sass", ".less"},
+	"go":         {".go"},
+	"html":       {".html", ".htm"},
+	"java":       {".java"},
+	"javascript": {".js", ".mjs", ".cjs", ".jsx"},
+	"json":       {".json"},
+	"kotlin":     {".kt", ".kts"},
+	"markdown":   {".md"},
+	"php":        {".php"},
+	"python":     {".py"},
+	"ruby":       {".rb"},
+	"rust":       {".rs"},
+	"shell":      {".sh", ".bash", ".zsh"},
+	"sql":        {".sql"},
+	"swift":      {".swift"},
+	"typescript": {".ts", ".tsx"},
+	"yaml":       {".yml", ".yaml"},
+}
+
+// IsLanguage checks if a language is known.
+func IsLanguage(lang string) bool {
+	_, ok := LanguageExtensions[strings.ToLower(lang)]
+	return ok
+}
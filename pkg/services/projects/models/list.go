@@ -1,8 +1,25 @@
 package models
 
 type CommonList struct {
-	TotalItems int `json:"totalItems"`
-	TotalPages int `json:"totalPages"`
-	Count      int `json:"count"`
-	Page       int `json:"page"`
+	TotalItems int     `json:"totalItems"`
+	TotalPages int     `json:"totalPages"`
+	Count      int     `json:"count"`
+	Page       int     `json:"page"`
+	Facets     *Facets `json:"facets,omitempty"`
+}
+
+// UpdatedBuckets are the values of the last updated facet, from the most recent.
+var UpdatedBuckets = []string{"week", "month", "year", "older"}
+
+// Facets holds the number of items in the full list per tag, detected
+// language and last updated bucket.
+type Facets struct {
+	Tags      []FacetCount `json:"tags"`
+	Languages []FacetCount `json:"languages"`
+	Updated   []FacetCount `json:"updated"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
 }
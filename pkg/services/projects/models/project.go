@@ -47,9 +47,14 @@ func (p *Project) ToJSON(w io.Writer) error {
 }
 
 type CodeFile struct {
-	Id      int    `json:"id,omitempty"`
-	Name    string `json:"name" validate:"max=50"`
-	Content string `json:"content" validate:"max=10000"`
+	Id       int    `json:"id,omitempty"`
+	Name     string `json:"name" validate:"max=50"`
+	Content  string `json:"content" validate:"max=10000"`
+	Language string `json:"language,omitempty"`
+}
+
+func (cf *CodeFile) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(cf)
 }
 
 type CodeFiles []CodeFile
@@ -7,23 +7,49 @@ import (
 
 type ProjectsList struct {
 	CommonList
-	Data []ProjectItem `json:"data"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+	DidYouMean string        `json:"didYouMean,omitempty"`
+	Data       []ProjectItem `json:"data"`
 }
 
 func (pl *ProjectsList) ToJSON(w io.Writer) error {
 	return json.NewEncoder(w).Encode(pl)
 }
 
+// ProjectItem is a project of the projects list. Cursor points right after
+// it, with the sort key it was listed by.
 type ProjectItem struct {
 	Id          int               `json:"id"`
 	Name        string            `json:"name"`
 	Description string            `json:"description"`
+	CreatedAt   int64             `json:"createdAt"`
 	UpdatedAt   int64             `json:"updatedAt"`
+	Rank        float64           `json:"rank,omitempty"`
+	Similarity  float64           `json:"similarity,omitempty"`
 	Files       []ProjectItemFile `json:"files"`
 	Tags        []Tag             `json:"tags"`
+	Cursor      Cursor            `json:"-"`
 }
 
 type ProjectItemFile struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
+	Id       int       `json:"id"`
+	Name     string    `json:"name"`
+	Language string    `json:"language,omitempty"`
+	Matched  bool      `json:"matched,omitempty"`
+	Snippets []Snippet `json:"snippets,omitempty"`
+}
+
+// Snippet is an excerpt of a code file around a search match.
+type Snippet struct {
+	FileId     int         `json:"fileId"`
+	Line       int         `json:"line"`
+	StartLine  int         `json:"startLine"`
+	Text       string      `json:"text"`
+	Highlights []Highlight `json:"highlights"`
+}
+
+// Highlight is the range, in characters, of a match within a text.
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }
@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// Filter is a node of the search query AST.
+type Filter interface {
+	filter()
+}
+
+// CompareOp is the comparison operator used by the date filters.
+type CompareOp string
+
+const (
+	CompareEq  CompareOp = "="
+	CompareGt  CompareOp = ">"
+	CompareGte CompareOp = ">="
+	CompareLt  CompareOp = "<"
+	CompareLte CompareOp = "<="
+)
+
+// AndFilter matches the projects matched by all its filters.
+type AndFilter struct {
+	Filters []Filter
+}
+
+// OrFilter matches the projects matched by any of its filters.
+type OrFilter struct {
+	Filters []Filter
+}
+
+// NotFilter matches the projects that are not matched by its filter.
+type NotFilter struct {
+	Filter Filter
+}
+
+// TextFilter matches free text against the project and its code files.
+// Alternatives are other texts meaning the same, matched as well.
+type TextFilter struct {
+	Value        string
+	Phrase       bool
+	Alternatives []string
+}
+
+// Values returns the text of the filter followed by its alternatives.
+func (f TextFilter) Values() []string {
+	return append([]string{f.Value}, f.Alternatives...)
+}
+
+// TagFilter matches the projects with a given tag.
+type TagFilter struct {
+	Name TagType
+}
+
+// FileFilter matches the projects with a code file name. The name may contain '*' wildcards.
+type FileFilter struct {
+	Name string
+}
+
+// LangFilter matches the projects with code files written in a given language.
+type LangFilter struct {
+	Language string
+}
+
+// UpdatedFilter matches the projects by their last update date.
+type UpdatedFilter struct {
+	Op   CompareOp
+	Time time.Time
+}
+
+// CreatedFilter matches the projects by their creation date.
+type CreatedFilter struct {
+	Op   CompareOp
+	Time time.Time
+}
+
+func (AndFilter) filter()     {}
+func (OrFilter) filter()      {}
+func (NotFilter) filter()     {}
+func (TextFilter) filter()    {}
+func (TagFilter) filter()     {}
+func (FileFilter) filter()    {}
+func (LangFilter) filter()    {}
+func (UpdatedFilter) filter() {}
+func (CreatedFilter) filter() {}
+
+// PositiveFilters flattens the top level conjunction of a filter, leaving out the negated filters.
+func PositiveFilters(f Filter) []Filter {
+	switch f := f.(type) {
+	case nil, NotFilter:
+		return nil
+	case AndFilter:
+		var res []Filter
+		for _, child := range f.Filters {
+			res = append(res, PositiveFilters(child)...)
+		}
+		return res
+	default:
+		return []Filter{f}
+	}
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryError is returned when the search query can not be parsed.
+type QueryError struct {
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d near %q: %s", e.Pos, e.Token, e.Msg)
+}
+
+type queryToken struct {
+	pos    int
+	raw    string
+	negate bool
+	key    string
+	value  string
+	quoted bool
+}
+
+// ParseQuery parses a search query into a filter AST.
+//
+// The query is a list of space separated terms that must all match. A term is
+// either free text, a quoted phrase or a "key:value" filter (tag, file, lang,
+// updated), and it can be negated with a leading '-'. For example:
+//
+//	tag:LANGUAGE file:main.go lang:go updated:>2026-01-01 "exact phrase" -deprecated
+func ParseQuery(query string) (AndFilter, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return AndFilter{}, err
+	}
+	res := AndFilter{Filters: make([]Filter, 0, len(tokens))}
+	for _, tok := range tokens {
+		f, err := parseQueryToken(tok)
+		if err != nil {
+			return AndFilter{}, err
+		}
+		if tok.negate {
+			f = NotFilter{f}
+		}
+		res.Filters = append(res.Filters, f)
+	}
+	return res, nil
+}
+
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		tok := queryToken{pos: i}
+		start := i
+		if runes[i] == '-' {
+			tok.negate = true
+			i++
+		}
+		var value strings.Builder
+		for i < len(runes) && !unicode.IsSpace(runes[i]) {
+			switch {
+			case runes[i] == '"':
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, &QueryError{i, string(runes[i:]), "unterminated quote"}
+				}
+				value.WriteString(string(runes[i+1 : end]))
+				tok.quoted = true
+				i = end + 1
+			case runes[i] == ':' && tok.key == "" && !tok.quoted && isQueryKey(value.String()):
+				tok.key = strings.ToLower(value.String())
+				value.Reset()
+				i++
+			default:
+				value.WriteRune(runes[i])
+				i++
+			}
+		}
+		tok.raw = string(runes[start:i])
+		tok.value = value.String()
+		if tok.key == "" && tok.value == "" {
+			return nil, &QueryError{tok.pos, tok.raw, "empty term"}
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+func isQueryKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseQueryToken(tok queryToken) (Filter, error) {
+	if tok.key != "" && strings.TrimSpace(tok.value) == "" {
+		return nil, &QueryError{tok.pos, tok.raw, fmt.Sprintf("missing value for %q", tok.key)}
+	}
+	switch tok.key {
+	case "":
+		return TextFilter{Value: tok.value, Phrase: tok.quoted}, nil
+	case "tag":
+		return TagFilter{TagType(strings.ToUpper(tok.value))}, nil
+	case "file":
+		return FileFilter{tok.value}, nil
+	case "lang":
+		lang := strings.ToLower(tok.value)
+		if !IsLanguage(lang) {
+			return nil, &QueryError{tok.pos, tok.raw, fmt.Sprintf("unknown language %q", tok.value)}
+		}
+		return LangFilter{lang}, nil
+	case "updated":
+		op, t, err := parseDateComparison(tok.value)
+		if err != nil {
+			return nil, &QueryError{tok.pos, tok.raw, err.Error()}
+		}
+		return UpdatedFilter{op, t}, nil
+	default:
+		return nil, &QueryError{tok.pos, tok.raw, fmt.Sprintf("unknown filter %q", tok.key)}
+	}
+}
+
+func parseDateComparison(s string) (CompareOp, time.Time, error) {
+	op := CompareEq
+	for _, candidate := range []CompareOp{CompareGte, CompareLte, CompareGt, CompareLt, CompareEq} {
+		if strings.HasPrefix(s, string(candidate)) {
+			op = candidate
+			s = strings.TrimPrefix(s, string(candidate))
+			break
+		}
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		op, t = wholeDay(op, t)
+		return op, t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return op, t, nil
+	}
+	return op, time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", s)
+}
+
+// wholeDay makes a comparison with a date, which starts at midnight, take in
+// the whole day: "<=2026-01-01" becomes "<2026-01-02" and ">2026-01-01"
+// becomes ">=2026-01-02".
+func wholeDay(op CompareOp, day time.Time) (CompareOp, time.Time) {
+	switch op {
+	case CompareLte:
+		return CompareLt, day.AddDate(0, 0, 1)
+	case CompareGt:
+		return CompareGte, day.AddDate(0, 0, 1)
+	}
+	return op, day
+}
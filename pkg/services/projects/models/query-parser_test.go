@@ -0,0 +1,93 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tests := []struct {
+		query string
+		want  []Filter
+	}{
+		{"", []Filter{}},
+		{"  ", []Filter{}},
+		{"parser", []Filter{TextFilter{Value: "parser"}}},
+		{`"exact phrase"`, []Filter{TextFilter{Value: "exact phrase", Phrase: true}}},
+		{"-deprecated", []Filter{NotFilter{TextFilter{Value: "deprecated"}}}},
+		{"tag:language", []Filter{TagFilter{"LANGUAGE"}}},
+		{"TAG:language", []Filter{TagFilter{"LANGUAGE"}}},
+		{"file:main.go", []Filter{FileFilter{"main.go"}}},
+		{"lang:Go", []Filter{LangFilter{"go"}}},
+		{"-tag:arch", []Filter{NotFilter{TagFilter{"ARCH"}}}},
+		{`tag:"two words"`, []Filter{TagFilter{"TWO WORDS"}}},
+		{"updated:2026-01-02", []Filter{UpdatedFilter{CompareEq, day("2026-01-02")}}},
+		{"updated:>=2026-01-02", []Filter{UpdatedFilter{CompareGte, day("2026-01-02")}}},
+		{"updated:<2026-01-02", []Filter{UpdatedFilter{CompareLt, day("2026-01-02")}}},
+		{"updated:<=2026-01-02", []Filter{UpdatedFilter{CompareLt, day("2026-01-03")}}},
+		{"updated:>2026-01-02", []Filter{UpdatedFilter{CompareGte, day("2026-01-03")}}},
+		{
+			"updated:>2026-01-02T10:00:00Z",
+			[]Filter{UpdatedFilter{CompareGt, time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)}},
+		},
+		{
+			`tag:LANGUAGE lang:go "exact phrase" -deprecated`,
+			[]Filter{
+				TagFilter{"LANGUAGE"},
+				LangFilter{"go"},
+				TextFilter{Value: "exact phrase", Phrase: true},
+				NotFilter{TextFilter{Value: "deprecated"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) error = %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got.Filters, tt.want) {
+				t.Errorf("ParseQuery(%q) = %#v, want %#v", tt.query, got.Filters, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		token string
+	}{
+		{`"unterminated`, 0, `"unterminated`},
+		{`ok "unterminated`, 3, `"unterminated`},
+		{"-", 0, "-"},
+		{"ok -", 3, "-"},
+		{"tag:", 0, "tag:"},
+		{"ok file:", 3, "file:"},
+		{"lang:cobolx", 0, "lang:cobolx"},
+		{"ok unknown:x", 3, "unknown:x"},
+		{"a:b:c", 0, "a:b:c"},
+		{"updated:yesterday", 0, "updated:yesterday"},
+		{"ok -updated:>2026-13-01", 3, "-updated:>2026-13-01"},
+		{"é updated:x", 2, "updated:x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+			var qe *QueryError
+			if !errors.As(err, &qe) {
+				t.Fatalf("ParseQuery(%q) error = %v, want a QueryError", tt.query, err)
+			}
+			if qe.Pos != tt.pos || qe.Token != tt.token {
+				t.Errorf("ParseQuery(%q) error at %d near %q, want %d near %q", tt.query, qe.Pos, qe.Token, tt.pos, tt.token)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"lastimplementation.com/internal/validate"
+)
+
+const defaultRevisionsLimit = 20
+
+// RevisionRef identifies a revision of a project.
+type RevisionRef struct {
+	Number    int   `json:"number"`
+	CreatedAt int64 `json:"createdAt"`
+}
+
+// FileRevision is a code file as it was recorded in a revision.
+type FileRevision struct {
+	RevisionRef
+	Name    string
+	Content string
+}
+
+// FileRef identifies a code file of a project.
+type FileRef struct {
+	ProjectId int
+	FileId    int
+	Name      string
+}
+
+// Revision summarizes a revision of a project: the number of code files it
+// holds and the names of the files added, changed, removed and renamed since
+// the previous revision.
+type Revision struct {
+	RevisionRef
+	FileCount int          `json:"fileCount"`
+	Added     []string     `json:"added"`
+	Changed   []string     `json:"changed"`
+	Removed   []string     `json:"removed"`
+	Renamed   []FileRename `json:"renamed"`
+}
+
+// FileRename is a code file renamed between two revisions.
+type FileRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type RevisionsList struct {
+	CommonList
+	Data []Revision `json:"data"`
+}
+
+func (rl *RevisionsList) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(rl)
+}
+
+type RevisionsQP struct {
+	Page  int `validate:"min=1"`
+	Limit int `validate:"min=1,max=100"`
+}
+
+func NewRevisionsQP(values url.Values) (RevisionsQP, error) {
+	var res RevisionsQP
+	if page := values.Get("page"); page != "" {
+		pageNum, err := strconv.Atoi(page)
+		if err != nil {
+			return res, err
+		}
+		res.Page = pageNum
+	} else {
+		res.Page = defaultPage
+	}
+	if limit := values.Get("limit"); limit != "" {
+		limitNum, err := strconv.Atoi(limit)
+		if err != nil {
+			return res, err
+		}
+		res.Limit = limitNum
+	} else {
+		res.Limit = defaultRevisionsLimit
+	}
+	if err := validate.Get().Struct(res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// RestoreQP holds the names of the code files to restore from a revision. No
+// names restore them all.
+type RestoreQP struct {
+	Files []string `validate:"max=50,dive,min=1,max=200"`
+}
+
+func NewRestoreQP(values url.Values) (RestoreQP, error) {
+	var res RestoreQP
+	for _, name := range strings.Split(values.Get("files"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			res.Files = append(res.Files, name)
+		}
+	}
+	if err := validate.Get().Struct(res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// SavedSearch is a projects search saved under a name. Its query holds the
+// query string of the projects list, as in "q=lang:go&tags=ARCHITECTURE".
+// LastSeen and LastSeenId are the high-water mark of the matches already
+// fetched: the update time and id of the last one.
+type SavedSearch struct {
+	Id         int       `json:"id"`
+	Name       string    `json:"name" validate:"min=1,max=100"`
+	Query      string    `json:"query" validate:"max=1000"`
+	LastSeen   time.Time `json:"-"`
+	LastSeenId int       `json:"-"`
+	LastSeenAt int64     `json:"lastSeenAt"`
+	CreatedAt  int64     `json:"createdAt"`
+}
+
+func (s *SavedSearch) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(s)
+}
+
+func (s *SavedSearch) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// SearchQP reads the projects search of a saved search.
+func (s SavedSearch) SearchQP() (SearchQP, error) {
+	values, err := url.ParseQuery(s.Query)
+	if err != nil {
+		return SearchQP{}, fmt.Errorf("invalid saved query: %w", err)
+	}
+	return NewSearchQP(values)
+}
+
+type SavedSearches []SavedSearch
+
+func (ss *SavedSearches) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(ss)
+}
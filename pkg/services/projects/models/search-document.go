@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SearchDocument is a project along with everything its search looks at.
+type SearchDocument struct {
+	Id          int
+	Name        string
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Tags        []Tag
+	Files       []CodeFile
+}
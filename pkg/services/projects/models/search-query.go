@@ -1,25 +1,69 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"lastimplementation.com/internal/validate"
 )
 
 const (
-	defaultPage  = 1
-	defaultLimit = 20
+	defaultPage    = 1
+	defaultLimit   = 20
+	defaultTagMode = TagModeAll
+)
+
+// SortField is the field by which the projects list is sorted.
+type SortField string
+
+const (
+	SortByName      SortField = "name"
+	SortByUpdatedAt SortField = "updatedAt"
+	SortByCreatedAt SortField = "createdAt"
+	SortByRelevance SortField = "relevance"
+	SortByFileCount SortField = "fileCount"
+)
+
+// SortOrder is the direction in which the projects list is sorted.
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// TagMode defines how the tags filter is matched against the project tags.
+type TagMode string
+
+const (
+	TagModeAll TagMode = "all"
+	TagModeAny TagMode = "any"
 )
 
 type SearchQP struct {
-	Query string `validate:"max=100"`
-	Page  int    `validate:"min=1,max=100"`
-	Limit int    `validate:"min=1,max=100"`
+	Query        string `validate:"max=100"`
+	Filter       Filter
+	Tags         []TagType `validate:"max=30,dive,min=1,max=50"`
+	ExcludedTags []TagType `validate:"max=30,dive,min=1,max=50"`
+	TagMode      TagMode   `validate:"oneof=all any"`
+	Sort         SortField `validate:"oneof=name updatedAt createdAt relevance fileCount"`
+	Order        SortOrder `validate:"oneof=asc desc"`
+	UpdatedFrom  *time.Time
+	UpdatedTo    *time.Time
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	Cursor       *Cursor
+	Page         int `validate:"min=1,max=100"`
+	Limit        int `validate:"min=1,max=100"`
 }
 
-func NewSearchQP(query, page, limit string) (SearchQP, error) {
+func NewSearchQP(values url.Values) (SearchQP, error) {
 	var res SearchQP
-	if page != "" {
+	if page := values.Get("page"); page != "" {
 		pageNum, err := strconv.Atoi(page)
 		if err != nil {
 			return res, err
@@ -28,7 +72,7 @@ func NewSearchQP(query, page, limit string) (SearchQP, error) {
 	} else {
 		res.Page = defaultPage
 	}
-	if limit != "" {
+	if limit := values.Get("limit"); limit != "" {
 		limitNum, err := strconv.Atoi(limit)
 		if err != nil {
 			return res, err
@@ -37,9 +81,139 @@ func NewSearchQP(query, page, limit string) (SearchQP, error) {
 	} else {
 		res.Limit = defaultLimit
 	}
-	res.Query = query
+	if tagMode := values.Get("tagMode"); tagMode != "" {
+		res.TagMode = TagMode(tagMode)
+	} else {
+		res.TagMode = defaultTagMode
+	}
+	for _, tag := range strings.Split(values.Get("tags"), ",") {
+		tag = strings.ToUpper(strings.TrimSpace(tag))
+		if strings.HasPrefix(tag, "-") {
+			res.ExcludedTags = append(res.ExcludedTags, TagType(strings.TrimPrefix(tag, "-")))
+		} else if tag != "" {
+			res.Tags = append(res.Tags, TagType(tag))
+		}
+	}
+	if err := res.readDateRanges(values); err != nil {
+		return res, err
+	}
+	res.Query = values.Get("q")
+	res.Sort = SortField(values.Get("sort"))
+	res.Order = SortOrder(values.Get("order"))
+	// The query length is checked before parsing it, so long queries are
+	// rejected without being parsed.
+	if err := validate.Get().Var(res.Query, "max=100"); err != nil {
+		return res, err
+	}
+	filter, err := ParseQuery(res.Query)
+	if err != nil {
+		return res, err
+	}
+	res.setDefaultSort(filter)
 	if err := validate.Get().Struct(res); err != nil {
 		return res, err
 	}
+	if token := values.Get("cursor"); token != "" {
+		cursor, err := DecodeCursor(token)
+		if err != nil {
+			return res, err
+		}
+		if cursor.Sort != res.Sort || cursor.Order != res.Order || cursor.Scope != res.cursorScope() {
+			return res, errInvalidCursor
+		}
+		res.Cursor = &cursor
+	}
+	res.Filter = AndFilter{Filters: append(append(filter.Filters, res.tagsFilters()...), res.dateFilters()...)}
 	return res, nil
 }
+
+// setDefaultSort sorts by relevance when the query has free text, and by name
+// otherwise. Names are sorted in ascending order by default, while the
+// remaining fields show the highest values first.
+func (qp *SearchQP) setDefaultSort(filter AndFilter) {
+	if qp.Sort == "" {
+		qp.Sort = SortByName
+		for _, f := range filter.Filters {
+			if _, ok := f.(TextFilter); ok {
+				qp.Sort = SortByRelevance
+				break
+			}
+		}
+	}
+	if qp.Order == "" {
+		qp.Order = SortDesc
+		if qp.Sort == SortByName {
+			qp.Order = SortAsc
+		}
+	}
+}
+
+// tagsFilters returns the filters matching the included and excluded tags.
+func (qp SearchQP) tagsFilters() []Filter {
+	var res []Filter
+	if len(qp.Tags) > 0 {
+		tags := make([]Filter, len(qp.Tags))
+		for i, tag := range qp.Tags {
+			tags[i] = TagFilter{tag}
+		}
+		if qp.TagMode == TagModeAny {
+			res = append(res, OrFilter{tags})
+		} else {
+			res = append(res, tags...)
+		}
+	}
+	for _, tag := range qp.ExcludedTags {
+		res = append(res, NotFilter{TagFilter{tag}})
+	}
+	return res
+}
+
+// readDateRanges reads the RFC 3339 bounds of the updated and created date
+// ranges. Both bounds are inclusive, and either of them can be left out.
+func (qp *SearchQP) readDateRanges(values url.Values) error {
+	bounds := []struct {
+		param string
+		dst   **time.Time
+	}{
+		{"updatedFrom", &qp.UpdatedFrom},
+		{"updatedTo", &qp.UpdatedTo},
+		{"createdFrom", &qp.CreatedFrom},
+		{"createdTo", &qp.CreatedTo},
+	}
+	for _, b := range bounds {
+		v := values.Get(b.param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: expected an RFC 3339 time, such as 2026-01-02T15:04:05Z", b.param, v)
+		}
+		*b.dst = &t
+	}
+	if qp.UpdatedFrom != nil && qp.UpdatedTo != nil && qp.UpdatedFrom.After(*qp.UpdatedTo) {
+		return errors.New("invalid updated range: updatedFrom is after updatedTo")
+	}
+	if qp.CreatedFrom != nil && qp.CreatedTo != nil && qp.CreatedFrom.After(*qp.CreatedTo) {
+		return errors.New("invalid created range: createdFrom is after createdTo")
+	}
+	return nil
+}
+
+// dateFilters returns the filters matching the updated and created date ranges.
+func (qp SearchQP) dateFilters() []Filter {
+	var res []Filter
+	if qp.UpdatedFrom != nil {
+		res = append(res, UpdatedFilter{CompareGte, *qp.UpdatedFrom})
+	}
+	if qp.UpdatedTo != nil {
+		res = append(res, UpdatedFilter{CompareLte, *qp.UpdatedTo})
+	}
+	if qp.CreatedFrom != nil {
+		res = append(res, CreatedFilter{CompareGte, *qp.CreatedFrom})
+	}
+	if qp.CreatedTo != nil {
+		res = append(res, CreatedFilter{CompareLte, *qp.CreatedTo})
+	}
+	return res
+}
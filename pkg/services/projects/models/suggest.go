@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"lastimplementation.com/internal/validate"
+)
+
+const defaultSuggestLimit = 10
+
+type SuggestionKind string
+
+const (
+	SuggestionKindProject SuggestionKind = "project"
+	SuggestionKindTag     SuggestionKind = "tag"
+	SuggestionKindFile    SuggestionKind = "file"
+)
+
+// Suggestion is a completion of a search prefix. Code file suggestions also
+// hold the project they belong to.
+type Suggestion struct {
+	Kind      SuggestionKind `json:"kind"`
+	Id        int            `json:"id"`
+	Text      string         `json:"text"`
+	ProjectId int            `json:"projectId,omitempty"`
+}
+
+type Suggestions []Suggestion
+
+func (ss *Suggestions) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(ss)
+}
+
+type SuggestQP struct {
+	Prefix string `validate:"min=1,max=200"`
+	Limit  int    `validate:"min=1,max=50"`
+}
+
+func NewSuggestQP(values url.Values) (SuggestQP, error) {
+	var res SuggestQP
+	if limit := values.Get("limit"); limit != "" {
+		limitNum, err := strconv.Atoi(limit)
+		if err != nil {
+			return res, err
+		}
+		res.Limit = limitNum
+	} else {
+		res.Limit = defaultSuggestLimit
+	}
+	res.Prefix = strings.TrimSpace(values.Get("prefix"))
+	if err := validate.Get().Struct(res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
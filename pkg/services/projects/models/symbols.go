@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"lastimplementation.com/internal/validate"
+)
+
+const defaultSymbolsLimit = 20
+
+// symbolNameRe matches a symbol name, optionally preceded by its kind and, for
+// methods, by the receiver as written in the declaration, with or without the
+// receiver variable: "func (s *Server) Start" or "(*Server) Start".
+var symbolNameRe = regexp.MustCompile(`^(?:(\w+)\s+)?(?:\(\s*(?:\w+\s+)?([^()]*?)\s*\)\s*)?([^\s()]+)$`)
+
+type SymbolKind string
+
+const (
+	SymbolKindFunc   SymbolKind = "func"
+	SymbolKindMethod SymbolKind = "method"
+	SymbolKindType   SymbolKind = "type"
+	SymbolKindConst  SymbolKind = "const"
+)
+
+// Symbol is a top level declaration found in a code file.
+type Symbol struct {
+	Kind        SymbolKind `json:"kind"`
+	Name        string     `json:"name"`
+	Receiver    string     `json:"receiver,omitempty"`
+	Signature   string     `json:"signature"`
+	Digest      string     `json:"-"`
+	Line        int        `json:"line"`
+	ProjectId   int        `json:"projectId"`
+	ProjectName string     `json:"projectName"`
+	FileId      int        `json:"fileId"`
+	FileName    string     `json:"fileName"`
+}
+
+type Symbols []Symbol
+
+func (ss *Symbols) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(ss)
+}
+
+// Implementation is a function, method or type declaration, along with the
+// time its code file was last updated and the revision where it last changed.
+type Implementation struct {
+	Symbol
+	UpdatedAt int64        `json:"updatedAt"`
+	Revision  *RevisionRef `json:"revision,omitempty"`
+}
+
+type Implementations []Implementation
+
+func (is *Implementations) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(is)
+}
+
+type SymbolQP struct {
+	Kind     SymbolKind `validate:"omitempty,oneof=func method type const"`
+	Receiver string     `validate:"max=200"`
+	Name     string     `validate:"min=1,max=200"`
+	Limit    int        `validate:"min=1,max=100"`
+}
+
+// NewSymbolQP reads the symbols query. The name may be preceded by the kind of
+// the symbol, as in "func NewServer", and by the receiver of a method, as in
+// "func (s *Server) Start". A receiver makes the symbol a method, and it is
+// kept without its pointer, so pointer and value receivers match alike.
+func NewSymbolQP(values url.Values) (SymbolQP, error) {
+	var res SymbolQP
+	if limit := values.Get("limit"); limit != "" {
+		limitNum, err := strconv.Atoi(limit)
+		if err != nil {
+			return res, err
+		}
+		res.Limit = limitNum
+	} else {
+		res.Limit = defaultSymbolsLimit
+	}
+	name := strings.TrimSpace(values.Get("name"))
+	if name != "" {
+		m := symbolNameRe.FindStringSubmatch(name)
+		if m != nil {
+			res.Kind, res.Receiver, res.Name = SymbolKind(strings.ToLower(m[1])), strings.TrimSpace(strings.TrimPrefix(m[2], "*")), m[3]
+		}
+		if m == nil || (strings.Contains(name, "(") && res.Receiver == "") {
+			return res, fmt.Errorf("invalid symbol %q: expected a name, optionally preceded by a kind and a receiver, such as \"func (s *Server) Start\"", name)
+		}
+		if res.Receiver != "" {
+			if res.Kind != "" && res.Kind != SymbolKindFunc && res.Kind != SymbolKindMethod {
+				return res, fmt.Errorf("invalid symbol %q: only methods have a receiver", name)
+			}
+			res.Kind = SymbolKindMethod
+		}
+	}
+	if err := validate.Get().Struct(res); err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNewSymbolQP(t *testing.T) {
+	tests := []struct {
+		name string
+		want SymbolQP
+	}{
+		{"NewServer", SymbolQP{Name: "NewServer"}},
+		{"func NewServer", SymbolQP{Kind: SymbolKindFunc, Name: "NewServer"}},
+		{"TYPE  Server ", SymbolQP{Kind: SymbolKindType, Name: "Server"}},
+		{"func (s *Server) Start", SymbolQP{Kind: SymbolKindMethod, Receiver: "Server", Name: "Start"}},
+		{"method (s Server) Start", SymbolQP{Kind: SymbolKindMethod, Receiver: "Server", Name: "Start"}},
+		{"(*Server) Start", SymbolQP{Kind: SymbolKindMethod, Receiver: "Server", Name: "Start"}},
+		{"func ( s * Server )Start", SymbolQP{Kind: SymbolKindMethod, Receiver: "Server", Name: "Start"}},
+		{"(Server) Start", SymbolQP{Kind: SymbolKindMethod, Receiver: "Server", Name: "Start"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSymbolQP(url.Values{"name": {tt.name}})
+			if err != nil {
+				t.Fatalf("NewSymbolQP() error = %v", err)
+			}
+			tt.want.Limit = defaultSymbolsLimit
+			if got != tt.want {
+				t.Errorf("NewSymbolQP() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSymbolQPRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		values url.Values
+	}{
+		{"empty", url.Values{}},
+		{"unknown kind", url.Values{"name": {"var Server"}}},
+		{"several words", url.Values{"name": {"func Server Start"}}},
+		{"missing name", url.Values{"name": {"func (s *Server)"}}},
+		{"empty receiver", url.Values{"name": {"func () Start"}}},
+		{"receiver of a type", url.Values{"name": {"type (s *Server) Start"}}},
+		{"unbalanced receiver", url.Values{"name": {"func (s *Server Start"}}},
+		{"limit", url.Values{"name": {"Server"}, "limit": {"1000"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := NewSymbolQP(tt.values); err == nil {
+				t.Errorf("NewSymbolQP(%v) = %+v, want an error", tt.values, got)
+			}
+		})
+	}
+}
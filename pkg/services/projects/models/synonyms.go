@@ -0,0 +1,144 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maximumSynonymVariants bounds the number of alternative texts a free text
+// filter is expanded into.
+const maximumSynonymVariants = 10
+
+// Synonym is an entry of the synonyms dictionary: a term along with the words
+// meaning the same. Synonyms work both ways, so searching any of the words
+// also searches the others.
+type Synonym struct {
+	Id       int      `json:"id"`
+	Term     string   `json:"term" validate:"min=1,max=50"`
+	Synonyms []string `json:"synonyms" validate:"min=1,max=20,dive,min=1,max=50"`
+}
+
+func (s *Synonym) FromJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(s)
+}
+
+func (s *Synonym) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// Normalize lower cases the term and its synonyms, dropping the blank and repeated ones.
+func (s *Synonym) Normalize() {
+	s.Term = strings.ToLower(strings.TrimSpace(s.Term))
+	seen := map[string]struct{}{s.Term: {}}
+	synonyms := make([]string, 0, len(s.Synonyms))
+	for _, syn := range s.Synonyms {
+		syn = strings.ToLower(strings.TrimSpace(syn))
+		if _, ok := seen[syn]; ok || syn == "" {
+			continue
+		}
+		seen[syn] = struct{}{}
+		synonyms = append(synonyms, syn)
+	}
+	s.Synonyms = synonyms
+}
+
+type Synonyms []Synonym
+
+func (ss *Synonyms) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(ss)
+}
+
+// SynonymDictionary maps every lower cased word to the words meaning the same.
+type SynonymDictionary map[string][]string
+
+// NewSynonymDictionary builds the dictionary of the synonyms, linking every word of an entry to the rest.
+func NewSynonymDictionary(entries []Synonym) SynonymDictionary {
+	sets := make(map[string]map[string]struct{})
+	for _, e := range entries {
+		words := append([]string{e.Term}, e.Synonyms...)
+		for _, w := range words {
+			if sets[w] == nil {
+				sets[w] = make(map[string]struct{})
+			}
+			for _, other := range words {
+				if other != w {
+					sets[w][other] = struct{}{}
+				}
+			}
+		}
+	}
+	res := make(SynonymDictionary, len(sets))
+	for w, set := range sets {
+		for other := range set {
+			res[w] = append(res[w], other)
+		}
+		sort.Strings(res[w])
+	}
+	return res
+}
+
+// Expand adds the synonyms to a filter. The free text filters get the texts
+// resulting from replacing their words by synonyms as alternatives, and the
+// tag filters match the synonym tags as well.
+func (d SynonymDictionary) Expand(f Filter) Filter {
+	if len(d) == 0 {
+		return f
+	}
+	switch f := f.(type) {
+	case AndFilter:
+		return AndFilter{d.expandAll(f.Filters)}
+	case OrFilter:
+		return OrFilter{d.expandAll(f.Filters)}
+	case NotFilter:
+		return NotFilter{d.Expand(f.Filter)}
+	case TextFilter:
+		f.Alternatives = d.variants(f)
+		return f
+	case TagFilter:
+		syns := d[strings.ToLower(string(f.Name))]
+		if len(syns) == 0 {
+			return f
+		}
+		tags := []Filter{f}
+		for _, syn := range syns {
+			tags = append(tags, TagFilter{TagType(strings.ToUpper(syn))})
+		}
+		return OrFilter{tags}
+	}
+	return f
+}
+
+func (d SynonymDictionary) expandAll(filters []Filter) []Filter {
+	res := make([]Filter, len(filters))
+	for i, f := range filters {
+		res[i] = d.Expand(f)
+	}
+	return res
+}
+
+// variants returns the texts resulting from replacing the words of a text
+// filter by their synonyms. Phrases are only replaced as a whole.
+func (d SynonymDictionary) variants(f TextFilter) []string {
+	text := strings.ToLower(f.Value)
+	if f.Phrase {
+		return append([]string{}, d[text]...)
+	}
+	variants := []string{""}
+	for _, word := range strings.Fields(text) {
+		options := append([]string{word}, d[word]...)
+		var next []string
+		for _, v := range variants {
+			for _, o := range options {
+				if len(next) == maximumSynonymVariants+1 {
+					break
+				}
+				next = append(next, strings.TrimSpace(v+" "+o))
+			}
+		}
+		variants = next
+	}
+	// The first variant keeps every word as it was.
+	return variants[1:]
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testSynonyms = []Synonym{
+	{Term: "db", Synonyms: []string{"database"}},
+	{Term: "js", Synonyms: []string{"javascript", "ecmascript"}},
+	{Term: "arch", Synonyms: []string{"architecture"}},
+	{Term: "database", Synonyms: []string{"datastore"}},
+}
+
+func TestNewSynonymDictionary(t *testing.T) {
+	got := NewSynonymDictionary(testSynonyms)
+	want := SynonymDictionary{
+		"db":           {"database"},
+		"database":     {"datastore", "db"},
+		"datastore":    {"database"},
+		"js":           {"ecmascript", "javascript"},
+		"javascript":   {"ecmascript", "js"},
+		"ecmascript":   {"javascript", "js"},
+		"arch":         {"architecture"},
+		"architecture": {"arch"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewSynonymDictionary() = %v, want %v", got, want)
+	}
+}
+
+func TestSynonymDictionaryExpand(t *testing.T) {
+	d := NewSynonymDictionary(testSynonyms)
+	tests := []struct {
+		name   string
+		filter Filter
+		want   Filter
+	}{
+		{"word without synonyms", TextFilter{Value: "parser"}, TextFilter{Value: "parser", Alternatives: []string{}}},
+		{"word", TextFilter{Value: "db"}, TextFilter{Value: "db", Alternatives: []string{"database"}}},
+		{"upper case word", TextFilter{Value: "DB"}, TextFilter{Value: "DB", Alternatives: []string{"database"}}},
+		{
+			"every word replaced",
+			TextFilter{Value: "js db"},
+			TextFilter{Value: "js db", Alternatives: []string{
+				"js database", "ecmascript db", "ecmascript database", "javascript db", "javascript database",
+			}},
+		},
+		{"phrase", TextFilter{Value: "db", Phrase: true}, TextFilter{Value: "db", Phrase: true, Alternatives: []string{"database"}}},
+		{"phrase replaced as a whole", TextFilter{Value: "js db", Phrase: true}, TextFilter{Value: "js db", Phrase: true, Alternatives: []string{}}},
+		{"tag", TagFilter{"ARCH"}, OrFilter{[]Filter{TagFilter{"ARCH"}, TagFilter{"ARCHITECTURE"}}}},
+		{"tag without synonyms", TagFilter{"LANGUAGE"}, TagFilter{"LANGUAGE"}},
+		{"other filters", FileFilter{"db.go"}, FileFilter{"db.go"}},
+		{
+			"nested",
+			AndFilter{[]Filter{NotFilter{TextFilter{Value: "db"}}, OrFilter{[]Filter{TagFilter{"ARCH"}, LangFilter{"js"}}}}},
+			AndFilter{[]Filter{
+				NotFilter{TextFilter{Value: "db", Alternatives: []string{"database"}}},
+				OrFilter{[]Filter{OrFilter{[]Filter{TagFilter{"ARCH"}, TagFilter{"ARCHITECTURE"}}}, LangFilter{"js"}}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.Expand(tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand(%+v) = %+v, want %+v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSynonymDictionaryExpandEmpty(t *testing.T) {
+	f := TextFilter{Value: "db"}
+	if got := (SynonymDictionary{}).Expand(f); !reflect.DeepEqual(got, f) {
+		t.Errorf("Expand(%+v) = %+v, want it unchanged", f, got)
+	}
+}
+
+func TestSynonymVariantsBound(t *testing.T) {
+	d := NewSynonymDictionary(testSynonyms)
+	got := d.variants(TextFilter{Value: "js js js"})
+	if len(got) != maximumSynonymVariants {
+		t.Fatalf("variants() returned %d texts, want %d", len(got), maximumSynonymVariants)
+	}
+	if got[0] != "js js ecmascript" {
+		t.Errorf("variants()[0] = %q, want %q", got[0], "js js ecmascript")
+	}
+}
@@ -2,24 +2,94 @@ package projects
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"lastimplementation.com/pkg/services/projects/diff"
 	"lastimplementation.com/pkg/services/projects/logger"
 	"lastimplementation.com/pkg/services/projects/models"
+	"lastimplementation.com/pkg/services/projects/search"
+	"lastimplementation.com/pkg/services/projects/symbols"
 )
 
+// maxFileRevisions is the number of latest revisions of a project looked
+// through to follow the history of its code files.
+const maxFileRevisions = 200
+
+// matchTimeout bounds the matching of a changed project against the saved searches.
+const matchTimeout = 10 * time.Second
+
+// searchLogTimeout bounds the recording of a search in the search log.
+const searchLogTimeout = 5 * time.Second
+
+// searchLogTrimInterval is how often the search log is trimmed to its maximum size.
+const searchLogTrimInterval = 10 * time.Minute
+
+// errStopScan stops scanning the code files once the search has enough results.
+var errStopScan = errors.New("stop scanning")
+
 type Repo interface {
 	Reset(ctx context.Context) error
+	Migrate(ctx context.Context) error
 	Get(ctx context.Context, id int) (models.Project, error)
-	GetAll(ctx context.Context, q string, page, limit int) (models.ProjectsList, error)
+	GetAll(ctx context.Context, qp models.SearchQP) (models.ProjectsList, error)
 	Add(ctx context.Context, project models.Project) (int, error)
 	Update(ctx context.Context, id int, details models.ProjectDetails) error
 	Delete(ctx context.Context, id int) error
 	UpdateFiles(ctx context.Context, projectId int, files []models.CodeFile) error
 	GetFiles(ctx context.Context, projectId int) (models.CodeFiles, error)
+	ScanFiles(ctx context.Context, fn func(models.ProjectFile) error) error
+	ScanFileRevisions(ctx context.Context, fn func(models.ProjectFileRevision) error) error
+	FindSymbols(ctx context.Context, qp models.SymbolQP) (models.Symbols, error)
+	FindImplementations(ctx context.Context, qp models.SymbolQP) (models.Implementations, error)
+	GetFileRevisions(ctx context.Context, files []models.FileRef, limit int) ([][]models.FileRevision, error)
+	GetRevisions(ctx context.Context, projectId int, qp models.RevisionsQP) (models.RevisionsList, error)
+	GetRevisionFiles(ctx context.Context, projectId, revision int) (models.CodeFiles, error)
+	RestoreRevision(ctx context.Context, projectId, revision int, names []string) error
+	Suggest(ctx context.Context, qp models.SuggestQP) (models.Suggestions, error)
+	AddSavedSearch(ctx context.Context, search models.SavedSearch) (models.SavedSearch, error)
+	GetSavedSearches(ctx context.Context) (models.SavedSearches, error)
+	GetSavedSearch(ctx context.Context, id int) (models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, id int) error
+	MarkSavedSearchSeen(ctx context.Context, id int, seenAt time.Time, seenId int) error
+	MatchSavedSearches(ctx context.Context, projectId int, filters map[int]models.Filter) ([]int, error)
+	FindDuplicates(ctx context.Context, projectId, fileId int, qp models.DuplicatesQP) (models.DuplicateFiles, error)
+	FindDuplicatedSnippets(ctx context.Context, qp models.DuplicatesQP) (models.DuplicatedSnippets, error)
+	GetSearchDocuments(ctx context.Context) ([]models.SearchDocument, error)
+	GetSearchDocument(ctx context.Context, id int) (models.SearchDocument, error)
+	LogSearch(ctx context.Context, entry models.SearchLogEntry) error
+	TrimSearchLog(ctx context.Context) error
+	TopQueries(ctx context.Context, qp models.AnalyticsQP) (models.QueryStats, error)
+	ZeroResultQueries(ctx context.Context, qp models.AnalyticsQP) (models.QueryStats, error)
+	SearchLatency(ctx context.Context, qp models.AnalyticsQP) (models.LatencyStats, error)
+	AddSynonym(ctx context.Context, synonym models.Synonym) (models.Synonym, error)
+	GetSynonyms(ctx context.Context) (models.Synonyms, error)
+	UpdateSynonym(ctx context.Context, synonym models.Synonym) error
+	DeleteSynonym(ctx context.Context, id int) error
+	KnownNames(ctx context.Context, words []string) ([]string, error)
 }
 
+// Searcher is a search backend for the projects list, kept apart from the
+// repo. It is built from the repo documents and kept up to date by the service.
+type Searcher interface {
+	Search(ctx context.Context, qp models.SearchQP) (models.ProjectsList, error)
+	Index(doc models.SearchDocument)
+	Remove(id int)
+	Rebuild(ctx context.Context, load func() ([]models.SearchDocument, error)) error
+}
+
+// Search backends the projects list can be served from.
+const (
+	SearchBackendSQL    = "sql"
+	SearchBackendMemory = "memory"
+)
+
 type Service interface {
 	ResetRepo(ctx context.Context) error
+	MigrateRepo(ctx context.Context) error
 	Get(ctx context.Context, id int) (models.Project, error)
 	GetAll(ctx context.Context, qp models.SearchQP) (models.ProjectsList, error)
 	Add(ctx context.Context, project models.Project) (int, error)
@@ -27,19 +97,77 @@ type Service interface {
 	Delete(ctx context.Context, id int) error
 	GetFiles(ctx context.Context, projectId int) (models.CodeFiles, error)
 	UpdateFiles(ctx context.Context, projectId int, files []models.CodeFile) error
+	GetRevisions(ctx context.Context, projectId int, qp models.RevisionsQP) (models.RevisionsList, error)
+	GetRevisionFiles(ctx context.Context, projectId, revision int) (models.CodeFiles, error)
+	GetRevisionFile(ctx context.Context, projectId, revision, fileId int) (models.CodeFile, error)
+	GetDiff(ctx context.Context, projectId int, qp models.DiffQP) (models.Diff, error)
+	RestoreRevision(ctx context.Context, projectId, revision int, qp models.RestoreQP) error
+	GetBlame(ctx context.Context, projectId, fileId int) (models.Blame, error)
+	SearchCode(ctx context.Context, qp models.CodeSearchQP, emit func(models.CodeSearchFile) error) (models.CodeSearchSummary, error)
+	GetSymbols(ctx context.Context, qp models.SymbolQP) (models.Symbols, error)
+	GetImplementations(ctx context.Context, qp models.SymbolQP) (models.Implementations, error)
+	Suggest(ctx context.Context, qp models.SuggestQP) (models.Suggestions, error)
+	AddSavedSearch(ctx context.Context, search models.SavedSearch) (models.SavedSearch, error)
+	GetSavedSearches(ctx context.Context) (models.SavedSearches, error)
+	DeleteSavedSearch(ctx context.Context, id int) error
+	GetNewMatches(ctx context.Context, id int) (models.ProjectsList, error)
+	GetDuplicates(ctx context.Context, projectId, fileId int, qp models.DuplicatesQP) (models.DuplicateFiles, error)
+	GetDuplicatedSnippets(ctx context.Context, qp models.DuplicatesQP) (models.DuplicatedSnippets, error)
+	RebuildSearch(ctx context.Context) error
+	TrimSearchLog(ctx context.Context)
+	GetTopQueries(ctx context.Context, qp models.AnalyticsQP) (models.QueryStats, error)
+	GetZeroResultQueries(ctx context.Context, qp models.AnalyticsQP) (models.QueryStats, error)
+	GetSearchLatency(ctx context.Context, qp models.AnalyticsQP) (models.LatencyStats, error)
+	AddSynonym(ctx context.Context, synonym models.Synonym) (models.Synonym, error)
+	GetSynonyms(ctx context.Context) (models.Synonyms, error)
+	UpdateSynonym(ctx context.Context, synonym models.Synonym) error
+	DeleteSynonym(ctx context.Context, id int) error
+}
+
+// MatchHook is called when a newly created or updated project matches a saved search.
+type MatchHook func(ctx context.Context, search models.SavedSearch, projectId int)
+
+// Option configures the projects service.
+type Option func(*projects)
+
+// WithMatchHook sets the hook called on the projects matching a saved search.
+func WithMatchHook(hook MatchHook) Option {
+	return func(p *projects) {
+		p.matchHook = hook
+	}
+}
+
+// WithSearcher serves the projects list from a searcher instead of the repo.
+func WithSearcher(searcher Searcher) Option {
+	return func(p *projects) {
+		p.searcher = searcher
+	}
 }
 
 type projects struct {
-	l    logger.Logger
-	repo Repo
+	l         logger.Logger
+	repo      Repo
+	searcher  Searcher
+	matchHook MatchHook
+
+	// synonyms caches the synonyms dictionary until it changes. It is nil
+	// until loaded, and its generation counts the changes, so a dictionary
+	// loaded before a change is not cached after it.
+	synonymsMu  sync.RWMutex
+	synonyms    models.SynonymDictionary
+	synonymsGen int
 }
 
 // New creates a new projects service.
-func New(l logger.Logger, repo Repo) *projects {
-	return &projects{
+func New(l logger.Logger, repo Repo, opts ...Option) *projects {
+	p := &projects{
 		l:    l.WithPrefix("service"),
 		repo: repo,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // Reset resets the projects repo.
@@ -47,6 +175,11 @@ func (p *projects) ResetRepo(ctx context.Context) error {
 	return p.repo.Reset(ctx)
 }
 
+// MigrateRepo migrates the projects repo written by previous versions.
+func (p *projects) MigrateRepo(ctx context.Context) error {
+	return p.repo.Migrate(ctx)
+}
+
 // Get gets a single project.
 func (p *projects) Get(ctx context.Context, id int) (models.Project, error) {
 	return p.repo.Get(ctx, id)
@@ -54,22 +187,177 @@ func (p *projects) Get(ctx context.Context, id int) (models.Project, error) {
 
 // GetAll gets all the projects.
 func (p *projects) GetAll(ctx context.Context, qp models.SearchQP) (models.ProjectsList, error) {
-	return p.repo.GetAll(ctx, qp.Query, qp.Page, qp.Limit)
+	start := time.Now()
+	qp, err := p.expandSynonyms(ctx, qp)
+	if err != nil {
+		return models.ProjectsList{}, err
+	}
+
+	var pl models.ProjectsList
+	if p.searcher != nil {
+		pl, err = p.searcher.Search(ctx, qp)
+	} else {
+		pl, err = p.repo.GetAll(ctx, qp)
+	}
+	if err == nil && pl.TotalItems == 0 {
+		pl.DidYouMean = p.didYouMean(ctx, qp)
+	}
+	p.logSearch(models.SearchLogEntry{
+		Kind:    models.SearchKindProjects,
+		Query:   models.NormalizeQuery(qp.Query),
+		Filters: qp.LogFilters(),
+		Results: pl.TotalItems,
+		Latency: time.Since(start),
+		Outcome: searchOutcome(ctx, err, false),
+	})
+	return pl, err
+}
+
+// didYouMean suggests a query replacing the free text words of a query that
+// found nothing with the closest names of projects, tags or symbols. It
+// returns an empty string when there is nothing better to suggest.
+func (p *projects) didYouMean(ctx context.Context, qp models.SearchQP) string {
+	var words []string
+	for _, f := range models.PositiveFilters(qp.Filter) {
+		if text, ok := f.(models.TextFilter); ok {
+			words = append(words, search.Terms(strings.ToLower(text.Value), false)...)
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	names, err := p.repo.KnownNames(ctx, words)
+	if err != nil {
+		p.l.Error("getting known names", err)
+		return ""
+	}
+
+	suggestion, changed := qp.Query, false
+	for _, w := range words {
+		best, ok := search.Closest(w, names)
+		if !ok || best == w {
+			continue
+		}
+		re := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(w) + `\b`)
+		suggestion, changed = re.ReplaceAllLiteralString(suggestion, best), true
+	}
+	if !changed {
+		return ""
+	}
+	return suggestion
+}
+
+// expandSynonyms expands the free text of a search with its synonyms. Every
+// search path goes through it, so a saved search matches the same projects as
+// the projects list.
+func (p *projects) expandSynonyms(ctx context.Context, qp models.SearchQP) (models.SearchQP, error) {
+	synonyms, err := p.synonymDictionary(ctx)
+	if err != nil {
+		return qp, err
+	}
+	qp.Filter = synonyms.Expand(qp.Filter)
+	return qp, nil
+}
+
+// synonymDictionary returns the synonyms dictionary, loading it on first use.
+func (p *projects) synonymDictionary(ctx context.Context) (models.SynonymDictionary, error) {
+	p.synonymsMu.RLock()
+	dict, gen := p.synonyms, p.synonymsGen
+	p.synonymsMu.RUnlock()
+	if dict != nil {
+		return dict, nil
+	}
+
+	synonyms, err := p.repo.GetSynonyms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dict = models.NewSynonymDictionary(synonyms)
+	p.synonymsMu.Lock()
+	if p.synonymsGen == gen {
+		p.synonyms = dict
+	}
+	p.synonymsMu.Unlock()
+	return dict, nil
+}
+
+// invalidateSynonyms drops the cached synonyms dictionary, so the next search loads it again.
+func (p *projects) invalidateSynonyms() {
+	p.synonymsMu.Lock()
+	p.synonyms = nil
+	p.synonymsGen++
+	p.synonymsMu.Unlock()
+}
+
+// logSearch records a search in the search log. It runs in the background,
+// with its own context, so searches that failed or ran out of time are logged
+// too and the search never waits for it. Failures are only logged.
+func (p *projects) logSearch(entry models.SearchLogEntry) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), searchLogTimeout)
+		defer cancel()
+		if err := p.repo.LogSearch(ctx, entry); err != nil {
+			p.l.Error("logging search", err)
+		}
+	}()
+}
+
+// searchOutcome tells how a search ended from its error and context.
+func searchOutcome(ctx context.Context, err error, timedOut bool) models.SearchOutcome {
+	switch {
+	case timedOut || errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded:
+		return models.SearchOutcomeTimeout
+	case err != nil:
+		return models.SearchOutcomeError
+	}
+	return models.SearchOutcomeOK
+}
+
+// TrimSearchLog trims the search log to its maximum size every
+// searchLogTrimInterval, until the context is done.
+func (p *projects) TrimSearchLog(ctx context.Context) {
+	ticker := time.NewTicker(searchLogTrimInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.repo.TrimSearchLog(ctx); err != nil {
+				p.l.Error("trimming search log", err)
+			}
+		}
+	}
 }
 
 // Add adds a new project.
 func (p *projects) Add(ctx context.Context, project models.Project) (int, error) {
-	return p.repo.Add(ctx, project)
+	id, err := p.repo.Add(ctx, project)
+	if err != nil {
+		return id, err
+	}
+	p.projectChanged(ctx, id)
+	return id, nil
 }
 
 // Update updates an existing project.
 func (p *projects) Update(ctx context.Context, id int, details models.ProjectDetails) error {
-	return p.repo.Update(ctx, id, details)
+	if err := p.repo.Update(ctx, id, details); err != nil {
+		return err
+	}
+	p.projectChanged(ctx, id)
+	return nil
 }
 
 // Delete deletes an existing project.
 func (p *projects) Delete(ctx context.Context, id int) error {
-	return p.repo.Delete(ctx, id)
+	if err := p.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if p.searcher != nil {
+		p.searcher.Remove(id)
+	}
+	return nil
 }
 
 // GetFiles returns a list of the files that exists on a project.
@@ -79,5 +367,472 @@ func (p *projects) GetFiles(ctx context.Context, projectId int) (models.CodeFile
 
 // UpdateFiles updates the code files on a project.
 func (p *projects) UpdateFiles(ctx context.Context, projectId int, files []models.CodeFile) error {
-	return p.repo.UpdateFiles(ctx, projectId, files)
+	if err := p.repo.UpdateFiles(ctx, projectId, files); err != nil {
+		return err
+	}
+	p.projectChanged(ctx, projectId)
+	return nil
+}
+
+// GetRevisions gets a page of the revisions of a project, from newest to oldest.
+func (p *projects) GetRevisions(ctx context.Context, projectId int, qp models.RevisionsQP) (models.RevisionsList, error) {
+	return p.repo.GetRevisions(ctx, projectId, qp)
+}
+
+// GetRevisionFiles gets the code files of a project as they were in a revision.
+func (p *projects) GetRevisionFiles(ctx context.Context, projectId, revision int) (models.CodeFiles, error) {
+	return p.repo.GetRevisionFiles(ctx, projectId, revision)
+}
+
+// GetRevisionFile gets a code file of a project as it was in a revision.
+func (p *projects) GetRevisionFile(ctx context.Context, projectId, revision, fileId int) (models.CodeFile, error) {
+	files, err := p.repo.GetRevisionFiles(ctx, projectId, revision)
+	if err != nil {
+		return models.CodeFile{}, err
+	}
+	for _, f := range files {
+		if f.Id == fileId {
+			return f, nil
+		}
+	}
+	return models.CodeFile{}, ErrCodeFileNotFound
+}
+
+// RestoreRevision brings back the code files of a project, or some of them,
+// as they were in a revision. The restore is recorded as a new revision.
+func (p *projects) RestoreRevision(ctx context.Context, projectId, revision int, qp models.RestoreQP) error {
+	if err := p.repo.RestoreRevision(ctx, projectId, revision, qp.Files); err != nil {
+		return err
+	}
+	p.projectChanged(ctx, projectId)
+	return nil
+}
+
+// GetBlame tells the revision that last changed each line of a current code
+// file, going back up to maxFileRevisions revisions of its project.
+func (p *projects) GetBlame(ctx context.Context, projectId, fileId int) (models.Blame, error) {
+	files, err := p.repo.GetFiles(ctx, projectId)
+	if err != nil {
+		return models.Blame{}, err
+	}
+	for _, f := range files {
+		if f.Id != fileId {
+			continue
+		}
+		traces, err := p.repo.GetFileRevisions(ctx, []models.FileRef{{ProjectId: projectId, FileId: f.Id, Name: f.Name}}, maxFileRevisions)
+		if err != nil {
+			return models.Blame{}, err
+		}
+		trace := traces[0]
+		revisions := make([]models.FileRevision, len(trace))
+		for i, rev := range trace {
+			revisions[len(trace)-1-i] = rev
+		}
+		ranges, approximate := diff.Blame(revisions, f.Content)
+		return models.Blame{
+			FileId:      f.Id,
+			Name:        f.Name,
+			Ranges:      ranges,
+			Truncated:   len(trace) == maxFileRevisions,
+			Approximate: approximate,
+		}, nil
+	}
+	return models.Blame{}, ErrCodeFileNotFound
+}
+
+// GetDiff compares the code files of a project between two revisions, or
+// between a revision and the current files.
+func (p *projects) GetDiff(ctx context.Context, projectId int, qp models.DiffQP) (models.Diff, error) {
+	from, err := p.repo.GetRevisionFiles(ctx, projectId, qp.From)
+	if err != nil {
+		return models.Diff{}, err
+	}
+	var to models.CodeFiles
+	if qp.To == 0 {
+		to, err = p.repo.GetFiles(ctx, projectId)
+	} else {
+		to, err = p.repo.GetRevisionFiles(ctx, projectId, qp.To)
+	}
+	if err != nil {
+		return models.Diff{}, err
+	}
+	res := models.Diff{From: qp.From, To: qp.To, Files: diff.Files(from, to, qp.Context)}
+	if res.Files == nil {
+		res.Files = []models.FileDiff{}
+	}
+	return res, nil
+}
+
+// projectChanged reindexes a project that was created or updated, and
+// notifies the saved searches it matches.
+func (p *projects) projectChanged(ctx context.Context, projectId int) {
+	p.reindex(ctx, projectId)
+	p.notifyMatches(projectId)
+}
+
+// reindex updates a project on the searcher. Failures are only logged, since
+// the project is already saved, and fixed by the next rebuild.
+func (p *projects) reindex(ctx context.Context, projectId int) {
+	if p.searcher == nil {
+		return
+	}
+	doc, err := p.repo.GetSearchDocument(ctx, projectId)
+	if err != nil {
+		p.l.Error("reindexing project", projectId, err)
+		return
+	}
+	p.searcher.Index(doc)
+}
+
+// RebuildSearch rebuilds the searcher from the repo, while it keeps serving searches.
+// It runs at start up and on demand from the admin search routes.
+func (p *projects) RebuildSearch(ctx context.Context) error {
+	if p.searcher == nil {
+		return nil
+	}
+	return p.searcher.Rebuild(ctx, func() ([]models.SearchDocument, error) {
+		return p.repo.GetSearchDocuments(ctx)
+	})
+}
+
+// notifyMatches calls the match hook for every saved search matching a
+// project. It runs in the background, so the request that changed the project
+// does not wait for it. Failures are only logged, since the project is already
+// saved.
+func (p *projects) notifyMatches(projectId int) {
+	if p.matchHook == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), matchTimeout)
+		defer cancel()
+		searches, err := p.matchSavedSearches(ctx, projectId)
+		if err != nil {
+			p.l.Error("matching saved searches", projectId, err)
+			return
+		}
+		for _, search := range searches {
+			p.matchHook(ctx, search, projectId)
+		}
+	}()
+}
+
+// matchSavedSearches fetches the saved searches matching a project, with
+// their synonyms expanded. The saved searches that no longer parse are skipped.
+func (p *projects) matchSavedSearches(ctx context.Context, projectId int) (models.SavedSearches, error) {
+	searches, err := p.repo.GetSavedSearches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filters := make(map[int]models.Filter, len(searches))
+	byId := make(map[int]models.SavedSearch, len(searches))
+	for _, search := range searches {
+		qp, err := search.SearchQP()
+		if err != nil {
+			p.l.Debug("skipping saved search", search.Id, err)
+			continue
+		}
+		if qp, err = p.expandSynonyms(ctx, qp); err != nil {
+			return nil, err
+		}
+		filters[search.Id], byId[search.Id] = qp.Filter, search
+	}
+	ids, err := p.repo.MatchSavedSearches(ctx, projectId, filters)
+	if err != nil {
+		return nil, err
+	}
+	res := make(models.SavedSearches, len(ids))
+	for i, id := range ids {
+		res[i] = byId[id]
+	}
+	return res, nil
+}
+
+// SearchCode searches the code files content with a regular expression. The
+// matches are emitted file by file, until the search runs out of time or
+// reaches the maximum number of matches. In history mode, the code files of
+// every revision are searched instead of the current ones.
+func (p *projects) SearchCode(ctx context.Context, qp models.CodeSearchQP, emit func(models.CodeSearchFile) error) (models.CodeSearchSummary, error) {
+	start := time.Now()
+	summary, err := p.searchCode(ctx, qp, emit)
+	filters := ""
+	if qp.History {
+		filters = "history=true"
+	}
+	p.logSearch(models.SearchLogEntry{
+		Kind:    models.SearchKindCode,
+		Query:   qp.Pattern,
+		Filters: filters,
+		Results: summary.Matches,
+		Latency: time.Since(start),
+		Outcome: searchOutcome(ctx, err, summary.TimedOut),
+	})
+	return summary, err
+}
+
+func (p *projects) searchCode(ctx context.Context, qp models.CodeSearchQP, emit func(models.CodeSearchFile) error) (models.CodeSearchSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, models.CodeSearchTimeout)
+	defer cancel()
+
+	var summary models.CodeSearchSummary
+	var err error
+	if qp.History {
+		err = p.searchHistory(ctx, qp, emit, &summary)
+	} else {
+		err = p.searchFiles(ctx, qp, emit, &summary)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		summary.TimedOut = true
+		return summary, nil
+	}
+	if err != nil && !errors.Is(err, errStopScan) {
+		return summary, err
+	}
+	return summary, nil
+}
+
+func (p *projects) searchFiles(ctx context.Context, qp models.CodeSearchQP, emit func(models.CodeSearchFile) error, summary *models.CodeSearchSummary) error {
+	return p.repo.ScanFiles(ctx, func(f models.ProjectFile) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		matches, truncated := search.Grep(f.Content, qp.Regexp, qp.Limit-summary.Matches)
+		if len(matches) > 0 {
+			err := emit(models.CodeSearchFile{
+				ProjectId:   f.ProjectId,
+				ProjectName: f.ProjectName,
+				FileId:      f.Id,
+				FileName:    f.Name,
+				Matches:     matches,
+			})
+			if err != nil {
+				return err
+			}
+			summary.Files++
+			summary.Matches += len(matches)
+		}
+		if truncated {
+			summary.Truncated = true
+			return errStopScan
+		}
+		return nil
+	})
+}
+
+// searchHistory greps every revision of the code files, emitting each file
+// once with the revisions holding each matched line.
+func (p *projects) searchHistory(ctx context.Context, qp models.CodeSearchQP, emit func(models.CodeSearchFile) error, summary *models.CodeSearchSummary) error {
+	var file *models.ProjectFileRevision
+	var grep *search.RevisionsGrep
+	flush := func(partial bool) error {
+		if file == nil {
+			return nil
+		}
+		matches := grep.Matches(file.LatestRevision, !partial)
+		truncated := false
+		if remaining := qp.Limit - summary.Matches; len(matches) > remaining {
+			matches, truncated = matches[:remaining], true
+		}
+		if len(matches) > 0 {
+			err := emit(models.CodeSearchFile{
+				ProjectId:   file.ProjectId,
+				ProjectName: file.ProjectName,
+				FileId:      file.FileId,
+				FileName:    file.Name,
+				Matches:     matches,
+				Partial:     partial,
+			})
+			if err != nil {
+				return err
+			}
+			summary.Files++
+			summary.Matches += len(matches)
+		}
+		if truncated {
+			summary.Truncated = true
+			return errStopScan
+		}
+		return nil
+	}
+
+	err := p.repo.ScanFileRevisions(ctx, func(f models.ProjectFileRevision) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if file == nil || file.ProjectId != f.ProjectId || file.FileId != f.FileId || (f.FileId == 0 && file.Name != f.Name) {
+			if err := flush(false); err != nil {
+				return err
+			}
+			grep = search.NewRevisionsGrep(qp.Regexp, qp.Limit)
+		}
+		file = &f
+		grep.Add(f.Number, f.NextRevision, f.Content)
+		return nil
+	})
+	if ctx.Err() != nil {
+		// The matches of the file being scanned when the search timed out are
+		// still emitted, flagged as partial.
+		if err := flush(true); err != nil && !errors.Is(err, errStopScan) {
+			return err
+		}
+		return ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	return flush(false)
+}
+
+// GetSymbols finds the top level declarations of the code files by name.
+func (p *projects) GetSymbols(ctx context.Context, qp models.SymbolQP) (models.Symbols, error) {
+	return p.repo.FindSymbols(ctx, qp)
+}
+
+// GetImplementations finds every implementation of a function, method or type
+// across the projects, starting from the most recently updated. Each one links
+// to the revision where it last changed.
+func (p *projects) GetImplementations(ctx context.Context, qp models.SymbolQP) (models.Implementations, error) {
+	impls, err := p.repo.FindImplementations(ctx, qp)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]models.FileRef, len(impls))
+	for i, impl := range impls {
+		files[i] = models.FileRef{ProjectId: impl.ProjectId, FileId: impl.FileId, Name: impl.FileName}
+	}
+	revisions, err := p.repo.GetFileRevisions(ctx, files, maxFileRevisions)
+	if err != nil {
+		return nil, err
+	}
+	for i, impl := range impls {
+		impls[i].Revision = symbols.LastChanged(impl.Symbol, revisions[i])
+	}
+	return impls, nil
+}
+
+// Suggest completes a search prefix with project, tag and code file names.
+func (p *projects) Suggest(ctx context.Context, qp models.SuggestQP) (models.Suggestions, error) {
+	return p.repo.Suggest(ctx, qp)
+}
+
+// AddSavedSearch saves a projects search under a name.
+func (p *projects) AddSavedSearch(ctx context.Context, search models.SavedSearch) (models.SavedSearch, error) {
+	return p.repo.AddSavedSearch(ctx, search)
+}
+
+// GetSavedSearches lists the saved searches.
+func (p *projects) GetSavedSearches(ctx context.Context) (models.SavedSearches, error) {
+	return p.repo.GetSavedSearches(ctx)
+}
+
+// DeleteSavedSearch deletes a saved search.
+func (p *projects) DeleteSavedSearch(ctx context.Context, id int) error {
+	return p.repo.DeleteSavedSearch(ctx, id)
+}
+
+// GetNewMatches lists the projects matching a saved search that were created
+// or updated since they were last fetched, oldest first. The high-water mark
+// is the update time and id of the last project returned, as it was listed,
+// and the page starts right after it. So the matches that did not fit in the
+// page, or that share their update time with the last one, come along on the
+// next call, and a project changed meanwhile is listed again.
+func (p *projects) GetNewMatches(ctx context.Context, id int) (models.ProjectsList, error) {
+	search, err := p.repo.GetSavedSearch(ctx, id)
+	if err != nil {
+		return models.ProjectsList{}, err
+	}
+	qp, err := search.SearchQP()
+	if err != nil {
+		return models.ProjectsList{}, err
+	}
+	if qp, err = p.expandSynonyms(ctx, qp); err != nil {
+		return models.ProjectsList{}, err
+	}
+	qp.Filter = models.AndFilter{Filters: []models.Filter{
+		qp.Filter,
+		models.UpdatedFilter{Op: models.CompareGte, Time: search.LastSeen},
+	}}
+	qp.Sort, qp.Order, qp.Page = models.SortByUpdatedAt, models.SortAsc, 1
+	qp.Cursor = &models.Cursor{
+		Sort:  qp.Sort,
+		Order: qp.Order,
+		Key:   models.TimeCursorKey(search.LastSeen),
+		Id:    search.LastSeenId,
+	}
+
+	pl, err := p.repo.GetAll(ctx, qp)
+	if err != nil {
+		return models.ProjectsList{}, err
+	}
+	if len(pl.Data) == 0 {
+		return pl, nil
+	}
+	last := pl.Data[len(pl.Data)-1].Cursor
+	seenAt, err := last.SortValue()
+	if err != nil {
+		return models.ProjectsList{}, err
+	}
+	if err := p.repo.MarkSavedSearchSeen(ctx, id, seenAt.(time.Time), last.Id); err != nil {
+		return models.ProjectsList{}, err
+	}
+	pl.NextCursor = ""
+	return pl, nil
+}
+
+// GetDuplicates finds the near-identical copies of a code file in other projects.
+func (p *projects) GetDuplicates(ctx context.Context, projectId, fileId int, qp models.DuplicatesQP) (models.DuplicateFiles, error) {
+	return p.repo.FindDuplicates(ctx, projectId, fileId, qp)
+}
+
+// GetDuplicatedSnippets reports the snippets duplicated across the most code files.
+func (p *projects) GetDuplicatedSnippets(ctx context.Context, qp models.DuplicatesQP) (models.DuplicatedSnippets, error) {
+	return p.repo.FindDuplicatedSnippets(ctx, qp)
+}
+
+// GetTopQueries reports the most searched queries within a time window.
+func (p *projects) GetTopQueries(ctx context.Context, qp models.AnalyticsQP) (models.QueryStats, error) {
+	return p.repo.TopQueries(ctx, qp)
+}
+
+// GetZeroResultQueries reports the most searched queries within a time window that found nothing.
+func (p *projects) GetZeroResultQueries(ctx context.Context, qp models.AnalyticsQP) (models.QueryStats, error) {
+	return p.repo.ZeroResultQueries(ctx, qp)
+}
+
+// GetSearchLatency reports the latency percentiles of the searches within a time window.
+func (p *projects) GetSearchLatency(ctx context.Context, qp models.AnalyticsQP) (models.LatencyStats, error) {
+	return p.repo.SearchLatency(ctx, qp)
+}
+
+// AddSynonym adds an entry to the synonyms dictionary.
+func (p *projects) AddSynonym(ctx context.Context, synonym models.Synonym) (models.Synonym, error) {
+	res, err := p.repo.AddSynonym(ctx, synonym)
+	if err != nil {
+		return res, err
+	}
+	p.invalidateSynonyms()
+	return res, nil
+}
+
+// GetSynonyms lists the synonyms dictionary.
+func (p *projects) GetSynonyms(ctx context.Context) (models.Synonyms, error) {
+	return p.repo.GetSynonyms(ctx)
+}
+
+// UpdateSynonym replaces an entry of the synonyms dictionary.
+func (p *projects) UpdateSynonym(ctx context.Context, synonym models.Synonym) error {
+	if err := p.repo.UpdateSynonym(ctx, synonym); err != nil {
+		return err
+	}
+	p.invalidateSynonyms()
+	return nil
+}
+
+// DeleteSynonym deletes an entry of the synonyms dictionary.
+func (p *projects) DeleteSynonym(ctx context.Context, id int) error {
+	if err := p.repo.DeleteSynonym(ctx, id); err != nil {
+		return err
+	}
+	p.invalidateSynonyms()
+	return nil
 }
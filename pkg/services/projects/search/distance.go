@@ -0,0 +1,46 @@
+package search
+
+// Levenshtein returns the edit distance between two texts, in characters.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev, cur := make([]int, len(rb)+1), make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// MaximumEdits is the edit distance up to which a word is considered a typo of
+// another, growing with the length of the word.
+func MaximumEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 4:
+		return 1
+	case n <= 8:
+		return 2
+	}
+	return 3
+}
+
+// Closest returns the candidate closest to a word, as long as it is within
+// the maximum edits of the word. Ties go to the first candidate.
+func Closest(word string, candidates []string) (string, bool) {
+	best, bestDist := "", MaximumEdits(word)+1
+	for _, c := range candidates {
+		if d := Levenshtein(word, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best, best != ""
+}
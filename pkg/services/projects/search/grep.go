@@ -0,0 +1,33 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// Grep returns, at most, the first max lines of a content matched by a regular
+// expression. It also reports whether more lines would have matched.
+func Grep(content string, re *regexp.Regexp, max int) ([]models.CodeSearchMatch, bool) {
+	var res []models.CodeSearchMatch
+	for i, line := range strings.Split(content, "\n") {
+		locs := re.FindAllStringIndex(line, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		if len(res) == max {
+			return res, true
+		}
+		match := models.CodeSearchMatch{Line: i + 1, Text: line, Ranges: make([]models.Highlight, len(locs))}
+		for j, loc := range locs {
+			match.Ranges[j] = models.Highlight{
+				Start: utf8.RuneCountInString(line[:loc[0]]),
+				End:   utf8.RuneCountInString(line[:loc[1]]),
+			}
+		}
+		res = append(res, match)
+	}
+	return res, false
+}
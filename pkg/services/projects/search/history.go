@@ -0,0 +1,68 @@
+package search
+
+import (
+	"regexp"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// RevisionsGrep collects the lines matched by a regular expression across the
+// revisions of a code file. Lines are told apart by their text, so a line
+// that moved around is still the same match.
+type RevisionsGrep struct {
+	re      *regexp.Regexp
+	max     int
+	matches []*models.CodeSearchMatch
+	byText  map[string]*models.CodeSearchMatch
+	// removedIn holds, for each matched line, the revision of the project
+	// following the last revision holding it.
+	removedIn map[*models.CodeSearchMatch]int
+}
+
+// NewRevisionsGrep creates a grep over revisions, keeping at most max lines per revision.
+func NewRevisionsGrep(re *regexp.Regexp, max int) *RevisionsGrep {
+	return &RevisionsGrep{
+		re:        re,
+		max:       max,
+		byText:    make(map[string]*models.CodeSearchMatch),
+		removedIn: make(map[*models.CodeSearchMatch]int),
+	}
+}
+
+// Add greps a revision of the file. Revisions are added from oldest to
+// newest, along with the revision of the project that follows each one, or
+// zero for the latest.
+func (g *RevisionsGrep) Add(revision, nextRevision int, content string) {
+	found, _ := Grep(content, g.re, g.max)
+	for _, match := range found {
+		m, ok := g.byText[match.Text]
+		if !ok {
+			m = &models.CodeSearchMatch{Text: match.Text, Ranges: match.Ranges}
+			g.byText[match.Text] = m
+			g.matches = append(g.matches, m)
+		}
+		if n := len(m.Revisions); n > 0 && m.Revisions[n-1] == revision {
+			continue
+		}
+		m.Line = match.Line
+		m.Revisions = append(m.Revisions, revision)
+		g.removedIn[m] = nextRevision
+	}
+}
+
+// Matches returns the matched lines in the order they first appeared. Lines
+// missing from the latest revision of the project are flagged as deleted,
+// along with the revision that deleted them, either by changing the file or
+// by removing it. When not every revision of the file was added, the lines
+// are not flagged, since they may still be in the revisions left out.
+func (g *RevisionsGrep) Matches(latestRevision int, complete bool) []models.CodeSearchMatch {
+	res := make([]models.CodeSearchMatch, len(g.matches))
+	for i, m := range g.matches {
+		res[i] = *m
+		if complete && m.Revisions[len(m.Revisions)-1] < latestRevision {
+			res[i].Deleted = true
+			res[i].DeletedIn = g.removedIn[m]
+		}
+	}
+	return res
+}
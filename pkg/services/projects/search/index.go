@@ -0,0 +1,91 @@
+package search
+
+import (
+	"context"
+	"sync"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// MemoryIndex is an in-memory inverted index of the projects, ranking free
+// text matches with BM25. Reads and writes are safe to run concurrently. It
+// is rebuilt off to the side and swapped in once ready, so searches keep
+// being served in the meantime.
+type MemoryIndex struct {
+	mu sync.RWMutex
+	ix *invertedIndex
+	// journal holds the changes made while rebuilding, to replay them on the new index.
+	journal []func(*invertedIndex)
+
+	rebuildMu sync.Mutex
+}
+
+// NewMemoryIndex creates an empty in-memory index.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{ix: newInvertedIndex()}
+}
+
+// Search searches the projects list.
+func (m *MemoryIndex) Search(ctx context.Context, qp models.SearchQP) (models.ProjectsList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ix.search(qp)
+}
+
+// Index adds a project to the index, replacing its previous version. Versions
+// older than the indexed one, by their update time, and removed projects are
+// ignored, so concurrent changes can be indexed in any order.
+func (m *MemoryIndex) Index(doc models.SearchDocument) {
+	m.apply(func(ix *invertedIndex) { ix.add(doc) })
+}
+
+// Remove removes a project from the index.
+func (m *MemoryIndex) Remove(id int) {
+	m.apply(func(ix *invertedIndex) { ix.remove(id) })
+}
+
+func (m *MemoryIndex) apply(change func(*invertedIndex)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	change(m.ix)
+	if m.journal != nil {
+		m.journal = append(m.journal, change)
+	}
+}
+
+// Rebuild builds a new index from the documents returned by load and swaps it
+// with the current one. The changes made while loading are replayed on the new
+// index, so none of them are lost. The removed projects are dropped from the
+// tombstones then, as the documents loaded no longer hold them; only the ones
+// removed while loading are kept until the next rebuild.
+func (m *MemoryIndex) Rebuild(ctx context.Context, load func() ([]models.SearchDocument, error)) error {
+	m.rebuildMu.Lock()
+	defer m.rebuildMu.Unlock()
+
+	m.mu.Lock()
+	m.journal = []func(*invertedIndex){}
+	m.mu.Unlock()
+
+	next := newInvertedIndex()
+	docs, err := load()
+	if err == nil {
+		for _, doc := range docs {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+			next.add(doc)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.journal = nil
+		return err
+	}
+	for _, change := range m.journal {
+		change(next)
+	}
+	m.ix, m.journal = next, nil
+	return nil
+}
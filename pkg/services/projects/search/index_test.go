@@ -0,0 +1,214 @@
+package search
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+var epoch = time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+
+func doc(id int, name, description string, files ...string) models.SearchDocument {
+	res := models.SearchDocument{Id: id, Name: name, Description: description, CreatedAt: epoch, UpdatedAt: epoch}
+	for i, content := range files {
+		res.Files = append(res.Files, models.CodeFile{Id: id*10 + i, Name: "main.go", Content: content})
+	}
+	return res
+}
+
+func updated(sd models.SearchDocument, d time.Duration) models.SearchDocument {
+	sd.UpdatedAt = sd.UpdatedAt.Add(d)
+	return sd
+}
+
+func searchIds(t *testing.T, m *MemoryIndex, values url.Values) []int {
+	t.Helper()
+	qp, err := models.NewSearchQP(values)
+	if err != nil {
+		t.Fatalf("NewSearchQP() error = %v", err)
+	}
+	list, err := m.Search(context.Background(), qp)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	res := []int{}
+	for _, item := range list.Data {
+		res = append(res, item.Id)
+	}
+	return res
+}
+
+func TestSearchRanking(t *testing.T) {
+	tests := []struct {
+		name  string
+		docs  []models.SearchDocument
+		query string
+		want  []int
+	}{
+		{
+			"name over description over files",
+			[]models.SearchDocument{
+				doc(1, "parser", "tools", "tools"),
+				doc(2, "b2", "parser", "tools"),
+				doc(3, "c3", "tools", "parser"),
+			},
+			"parser",
+			[]int{1, 2, 3},
+		},
+		{
+			"repeated term",
+			[]models.SearchDocument{
+				doc(1, "a1", "parser parser docs"),
+				doc(2, "b2", "parser tools docs"),
+			},
+			"parser",
+			[]int{1, 2},
+		},
+		{
+			"shorter field",
+			[]models.SearchDocument{
+				doc(1, "a1", "parser tools"),
+				doc(2, "b2", "parser for every kind of config files"),
+			},
+			"parser",
+			[]int{1, 2},
+		},
+		{
+			"rarer term",
+			[]models.SearchDocument{
+				doc(1, "a1", "lexer lexer tools"),
+				doc(2, "b2", "lexer tools tools"),
+				doc(3, "c3", "tools docs"),
+				doc(4, "d4", "tools code"),
+			},
+			"lexer tools",
+			[]int{1, 2},
+		},
+		{
+			"every term required",
+			[]models.SearchDocument{
+				doc(1, "a1", "lexer tools"),
+				doc(2, "b2", "lexer parser"),
+			},
+			"lexer parser",
+			[]int{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemoryIndex()
+			for _, sd := range tt.docs {
+				m.Index(sd)
+			}
+			if got := searchIds(t, m, url.Values{"q": {tt.query}}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexOutOfOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes func(m *MemoryIndex)
+		query   string
+		want    []int
+	}{
+		{"later version", func(m *MemoryIndex) { m.Index(updated(doc(1, "a1", "lexer"), time.Minute)) }, "lexer", []int{1}},
+		{"replaced version", func(m *MemoryIndex) { m.Index(updated(doc(1, "a1", "lexer"), time.Minute)) }, "parser", []int{}},
+		{"stale version", func(m *MemoryIndex) { m.Index(updated(doc(1, "a1", "lexer"), -time.Minute)) }, "lexer", []int{}},
+		{"removed", func(m *MemoryIndex) { m.Remove(1) }, "parser", []int{}},
+		{"change after removal", func(m *MemoryIndex) {
+			m.Remove(1)
+			m.Index(updated(doc(1, "a1", "parser"), time.Minute))
+		}, "parser", []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemoryIndex()
+			m.Index(doc(1, "a1", "parser"))
+			tt.changes(m)
+			if got := searchIds(t, m, url.Values{"q": {tt.query}}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebuildReplaysJournal(t *testing.T) {
+	tests := []struct {
+		name        string
+		before      func(m *MemoryIndex)
+		loaded      []models.SearchDocument
+		during      func(m *MemoryIndex)
+		want        []int
+		wantRemoved int
+	}{
+		{
+			"loaded documents only",
+			func(m *MemoryIndex) {},
+			[]models.SearchDocument{doc(1, "a1", "parser"), doc(2, "b2", "parser")},
+			func(m *MemoryIndex) {},
+			[]int{1, 2},
+			0,
+		},
+		{
+			"added while loading",
+			func(m *MemoryIndex) {},
+			[]models.SearchDocument{doc(1, "a1", "parser")},
+			func(m *MemoryIndex) { m.Index(doc(2, "b2", "parser")) },
+			[]int{1, 2},
+			0,
+		},
+		{
+			"updated while loading",
+			func(m *MemoryIndex) {},
+			[]models.SearchDocument{doc(1, "a1", "parser"), doc(2, "b2", "parser")},
+			func(m *MemoryIndex) { m.Index(updated(doc(2, "b2", "lexer"), time.Minute)) },
+			[]int{1},
+			0,
+		},
+		{
+			"removed while loading",
+			func(m *MemoryIndex) {},
+			[]models.SearchDocument{doc(1, "a1", "parser"), doc(2, "b2", "parser")},
+			func(m *MemoryIndex) { m.Remove(2) },
+			[]int{1},
+			1,
+		},
+		{
+			"removed before loading",
+			func(m *MemoryIndex) {
+				m.Index(doc(2, "b2", "parser"))
+				m.Remove(2)
+			},
+			[]models.SearchDocument{doc(1, "a1", "parser")},
+			func(m *MemoryIndex) {},
+			[]int{1},
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemoryIndex()
+			tt.before(m)
+			err := m.Rebuild(context.Background(), func() ([]models.SearchDocument, error) {
+				tt.during(m)
+				return tt.loaded, nil
+			})
+			if err != nil {
+				t.Fatalf("Rebuild() error = %v", err)
+			}
+			if got := searchIds(t, m, url.Values{"q": {"parser"}, "sort": {"name"}}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+			if got := len(m.ix.removed); got != tt.wantRemoved {
+				t.Errorf("removed = %d, want %d", got, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,426 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+const (
+	// bm25K1 controls how quickly the score saturates as a term repeats.
+	bm25K1 = 1.2
+	// bm25B controls how much the score is normalized by the field length.
+	bm25B = 0.75
+)
+
+type field int
+
+const (
+	fieldName field = iota
+	fieldDescription
+	fieldFiles
+	fieldCount
+)
+
+// fieldWeights boost the matches in the project name over its description and code files.
+var fieldWeights = [fieldCount]float64{fieldName: 3, fieldDescription: 2, fieldFiles: 1}
+
+type document struct {
+	models.SearchDocument
+	lengths [fieldCount]int
+	terms   []string
+}
+
+// invertedIndex maps every term to the projects holding it. It is not safe
+// for concurrent use on its own.
+type invertedIndex struct {
+	docs map[int]*document
+	// removed holds the ids of the projects removed since the index was
+	// built, so late changes to them are not indexed again.
+	removed map[int]struct{}
+	// postings holds the frequency of a term per field of every project holding it.
+	postings     map[string]map[int]*[fieldCount]int
+	totalLengths [fieldCount]int
+}
+
+type hit struct {
+	doc        *document
+	rank       float64
+	similarity float64
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{
+		docs:     make(map[int]*document),
+		removed:  make(map[int]struct{}),
+		postings: make(map[string]map[int]*[fieldCount]int),
+	}
+}
+
+// add indexes a project, replacing its previous version unless that one was
+// updated later. Changes to removed projects are ignored, so the changes
+// applied out of order leave the latest version of every project.
+func (ix *invertedIndex) add(sd models.SearchDocument) {
+	if _, ok := ix.removed[sd.Id]; ok {
+		return
+	}
+	if prev, ok := ix.docs[sd.Id]; ok && prev.UpdatedAt.After(sd.UpdatedAt) {
+		return
+	}
+	ix.unindex(sd.Id)
+	doc := &document{SearchDocument: sd}
+	texts := [fieldCount][]string{
+		fieldName:        {sd.Name},
+		fieldDescription: {sd.Description},
+	}
+	for _, f := range sd.Files {
+		texts[fieldFiles] = append(texts[fieldFiles], f.Name, f.Content)
+	}
+	for fd, fieldTexts := range texts {
+		for _, text := range fieldTexts {
+			for _, term := range tokenize(text) {
+				docs, ok := ix.postings[term]
+				if !ok {
+					docs = make(map[int]*[fieldCount]int)
+					ix.postings[term] = docs
+				}
+				freqs, ok := docs[sd.Id]
+				if !ok {
+					freqs = &[fieldCount]int{}
+					docs[sd.Id] = freqs
+					doc.terms = append(doc.terms, term)
+				}
+				freqs[fd]++
+				doc.lengths[fd]++
+			}
+		}
+		ix.totalLengths[fd] += doc.lengths[fd]
+	}
+	ix.docs[sd.Id] = doc
+}
+
+func (ix *invertedIndex) remove(id int) {
+	ix.removed[id] = struct{}{}
+	ix.unindex(id)
+}
+
+func (ix *invertedIndex) unindex(id int) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	for fd := range ix.totalLengths {
+		ix.totalLengths[fd] -= doc.lengths[fd]
+	}
+	delete(ix.docs, id)
+}
+
+// has tells whether a project holds every term.
+func (ix *invertedIndex) has(doc *document, terms []string) bool {
+	for _, term := range terms {
+		if _, ok := ix.postings[term][doc.Id]; !ok {
+			return false
+		}
+	}
+	return len(terms) > 0
+}
+
+// score computes the BM25 score of a project for some terms, adding up the
+// weighted scores of its fields.
+func (ix *invertedIndex) score(doc *document, terms []string) float64 {
+	n := float64(len(ix.docs))
+	var res float64
+	for _, term := range terms {
+		docs := ix.postings[term]
+		freqs, ok := docs[doc.Id]
+		if !ok {
+			continue
+		}
+		df := float64(len(docs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for fd, tf := range freqs {
+			if tf == 0 {
+				continue
+			}
+			avg := float64(ix.totalLengths[fd]) / n
+			norm := float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*(1-bm25B+bm25B*float64(doc.lengths[fd])/avg))
+			res += fieldWeights[fd] * idf * norm
+		}
+	}
+	return res
+}
+
+// search filters, ranks, sorts and pages the projects the same way the SQL
+// search does. The relevance adds up the BM25 score and the trigram
+// similarity of the names to the free text.
+func (ix *invertedIndex) search(qp models.SearchQP) (models.ProjectsList, error) {
+	match, err := ix.compile(qp.Filter)
+	if err != nil {
+		return models.ProjectsList{}, err
+	}
+	var rankTerms, snippetTerms, texts []string
+	var fileMatches []func(models.CodeFile) bool
+	for _, f := range models.PositiveFilters(qp.Filter) {
+		switch f := f.(type) {
+		case models.TextFilter:
+			texts = append(texts, f.Value)
+			for _, v := range f.Values() {
+				rankTerms = append(rankTerms, tokenize(v)...)
+				snippetTerms = append(snippetTerms, Terms(v, f.Phrase)...)
+			}
+			fileMatches = append(fileMatches, fileTextMatch(f))
+		case models.FileFilter:
+			fileMatches = append(fileMatches, fileNameMatch(f.Name))
+		}
+	}
+
+	hits := make([]hit, 0)
+	for _, doc := range ix.docs {
+		if match(doc) {
+			hits = append(hits, hit{doc, ix.score(doc, rankTerms), similarity(doc, strings.Join(texts, " "))})
+		}
+	}
+
+	keyOf, ok := sortKeys[qp.Sort]
+	if !ok {
+		return models.ProjectsList{}, fmt.Errorf("unsupported sort field %q", qp.Sort)
+	}
+	dir := 1
+	if qp.Order == models.SortDesc {
+		dir = -1
+	}
+	cmp := func(a sortKey, aId int, b sortKey, bId int) int {
+		if c := a.compare(b); c != 0 {
+			return c * dir
+		}
+		switch {
+		case aId < bId:
+			return -dir
+		case aId > bId:
+			return dir
+		}
+		return 0
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		return cmp(keyOf(hits[i]), hits[i].doc.Id, keyOf(hits[j]), hits[j].doc.Id) < 0
+	})
+
+	start := 0
+	if qp.Cursor != nil {
+		key, err := decodeSortKey(*qp.Cursor)
+		if err != nil {
+			return models.ProjectsList{}, err
+		}
+		start = sort.Search(len(hits), func(i int) bool {
+			return cmp(keyOf(hits[i]), hits[i].doc.Id, key, qp.Cursor.Id) > 0
+		})
+	} else {
+		start = min((qp.Page-1)*qp.Limit, len(hits))
+	}
+	end := min(start+qp.Limit, len(hits))
+
+	res := models.ProjectsList{Data: make([]models.ProjectItem, 0, end-start)}
+	for _, h := range hits[start:end] {
+		item := projectItem(h, fileMatches, snippetTerms)
+		item.Cursor = qp.NextCursor(encodeSortKey(qp.Sort, keyOf(h)), h.doc.Id)
+		res.Data = append(res.Data, item)
+	}
+	if end < len(hits) && end > start {
+		last := hits[end-1]
+		res.NextCursor = qp.NextCursor(encodeSortKey(qp.Sort, keyOf(last)), last.doc.Id).Encode()
+	}
+	res.TotalItems = len(hits)
+	res.Facets = facets(hits)
+	if qp.Cursor == nil {
+		res.Page = qp.Page
+	}
+	res.Count = len(res.Data)
+	res.TotalPages = int(math.Ceil(float64(res.TotalItems) / float64(qp.Limit)))
+	return res, nil
+}
+
+func projectItem(h hit, fileMatches []func(models.CodeFile) bool, terms []string) models.ProjectItem {
+	item := models.ProjectItem{
+		Id:          h.doc.Id,
+		Name:        h.doc.Name,
+		Description: h.doc.Description,
+		CreatedAt:   h.doc.CreatedAt.Local().Unix(),
+		UpdatedAt:   h.doc.UpdatedAt.Local().Unix(),
+		Rank:        h.rank,
+		Similarity:  h.similarity,
+		Files:       make([]models.ProjectItemFile, len(h.doc.Files)),
+		Tags:        append([]models.Tag{}, h.doc.Tags...),
+	}
+	for i, f := range h.doc.Files {
+		item.Files[i] = models.ProjectItemFile{Id: f.Id, Name: f.Name, Language: f.Language}
+		for _, match := range fileMatches {
+			if match(f) {
+				item.Files[i].Matched = true
+				item.Files[i].Snippets = Snippets(f.Id, f.Content, terms)
+				break
+			}
+		}
+	}
+	return item
+}
+
+// similarity returns the highest trigram similarity of the project name and
+// its code file names to the free text of a search.
+func similarity(doc *document, text string) float64 {
+	if text == "" {
+		return 0
+	}
+	res := Similarity(doc.Name, text)
+	for _, f := range doc.Files {
+		res = math.Max(res, Similarity(f.Name, text))
+	}
+	return res
+}
+
+// facets counts the projects per tag, code file language and last updated bucket.
+func facets(hits []hit) *models.Facets {
+	tags, langs, updated := make(map[string]int), make(map[string]int), make(map[string]int)
+	now := time.Now().UTC()
+	for _, h := range hits {
+		seen := make(map[string]struct{})
+		for _, t := range h.doc.Tags {
+			if _, ok := seen[string(t.Name)]; !ok {
+				seen[string(t.Name)] = struct{}{}
+				tags[string(t.Name)]++
+			}
+		}
+		seen = make(map[string]struct{})
+		for _, f := range h.doc.Files {
+			if _, ok := seen[f.Language]; !ok && f.Language != "" {
+				seen[f.Language] = struct{}{}
+				langs[f.Language]++
+			}
+		}
+		updated[updatedBucket(h.doc.UpdatedAt, now)]++
+	}
+	res := &models.Facets{Tags: facetCounts(tags), Languages: facetCounts(langs)}
+	for _, bucket := range models.UpdatedBuckets {
+		res.Updated = append(res.Updated, models.FacetCount{Value: bucket, Count: updated[bucket]})
+	}
+	return res
+}
+
+func facetCounts(counts map[string]int) []models.FacetCount {
+	res := make([]models.FacetCount, 0, len(counts))
+	for value, count := range counts {
+		res = append(res, models.FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Value < res[j].Value
+	})
+	return res
+}
+
+// updatedBucket tells whether a time falls in the current week, starting on
+// Monday, month, year or before.
+func updatedBucket(t, now time.Time) string {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	week := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	switch {
+	case !t.Before(week):
+		return "week"
+	case !t.Before(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())):
+		return "month"
+	case !t.Before(time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())):
+		return "year"
+	}
+	return "older"
+}
+
+// sortKey is the value a project is sorted by: either a text or a number.
+type sortKey struct {
+	text    string
+	num     float64
+	numeric bool
+}
+
+// sortKeys maps the sort fields to the keys of the projects.
+var sortKeys = map[models.SortField]func(hit) sortKey{
+	models.SortByName:      func(h hit) sortKey { return sortKey{text: h.doc.Name} },
+	models.SortByUpdatedAt: func(h hit) sortKey { return numericKey(float64(h.doc.UpdatedAt.UnixMicro())) },
+	models.SortByCreatedAt: func(h hit) sortKey { return numericKey(float64(h.doc.CreatedAt.UnixMicro())) },
+	models.SortByRelevance: func(h hit) sortKey { return numericKey(h.rank + h.similarity) },
+	models.SortByFileCount: func(h hit) sortKey { return numericKey(float64(len(h.doc.Files))) },
+}
+
+func numericKey(num float64) sortKey {
+	return sortKey{num: num, numeric: true}
+}
+
+// compare compares texts ignoring case first, so the order resembles the
+// database collation.
+func (k sortKey) compare(o sortKey) int {
+	if k.numeric {
+		switch {
+		case k.num < o.num:
+			return -1
+		case k.num > o.num:
+			return 1
+		}
+		return 0
+	}
+	if c := strings.Compare(strings.ToLower(k.text), strings.ToLower(o.text)); c != 0 {
+		return c
+	}
+	return strings.Compare(k.text, o.text)
+}
+
+// encodeSortKey returns the cursor key of a sort key, in the format read by
+// models.Cursor.
+func encodeSortKey(field models.SortField, k sortKey) string {
+	switch field {
+	case models.SortByName:
+		return k.text
+	case models.SortByUpdatedAt, models.SortByCreatedAt:
+		return models.TimeCursorKey(time.UnixMicro(int64(k.num)))
+	case models.SortByFileCount:
+		return strconv.FormatInt(int64(k.num), 10)
+	}
+	return strconv.FormatFloat(k.num, 'g', -1, 64)
+}
+
+func decodeSortKey(cursor models.Cursor) (sortKey, error) {
+	value, err := cursor.SortValue()
+	if err != nil {
+		return sortKey{}, err
+	}
+	switch value := value.(type) {
+	case string:
+		return sortKey{text: value}, nil
+	case time.Time:
+		return numericKey(float64(value.UnixMicro())), nil
+	case float64:
+		return numericKey(value), nil
+	case int64:
+		return numericKey(float64(value)), nil
+	}
+	return sortKey{}, fmt.Errorf("unsupported cursor key %T", value)
+}
+
+// tokenize splits a text into lower cased terms.
+func tokenize(text string) []string {
+	terms := Terms(text, false)
+	for i, term := range terms {
+		terms[i] = strings.ToLower(term)
+	}
+	return terms
+}
@@ -0,0 +1,205 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// compile turns a filter into a predicate over the indexed projects.
+func (ix *invertedIndex) compile(f models.Filter) (func(*document) bool, error) {
+	switch f := f.(type) {
+	case nil:
+		return func(*document) bool { return true }, nil
+	case models.AndFilter:
+		preds, err := ix.compileAll(f.Filters)
+		if err != nil {
+			return nil, err
+		}
+		return func(doc *document) bool {
+			for _, pred := range preds {
+				if !pred(doc) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case models.OrFilter:
+		preds, err := ix.compileAll(f.Filters)
+		if err != nil {
+			return nil, err
+		}
+		return func(doc *document) bool {
+			for _, pred := range preds {
+				if pred(doc) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case models.NotFilter:
+		pred, err := ix.compile(f.Filter)
+		if err != nil {
+			return nil, err
+		}
+		return func(doc *document) bool { return !pred(doc) }, nil
+	case models.TextFilter:
+		fileMatch := fileTextMatch(f)
+		var terms [][]string
+		var values []string
+		for _, v := range f.Values() {
+			terms, values = append(terms, tokenize(v)), append(values, strings.ToLower(v))
+		}
+		return func(doc *document) bool {
+			for i, value := range values {
+				if f.Phrase {
+					if containsFold(doc.Name, value) || containsFold(doc.Description, value) {
+						return true
+					}
+				} else if ix.has(doc, terms[i]) {
+					return true
+				}
+				if nameMatch(doc.Name, value) {
+					return true
+				}
+			}
+			for _, file := range doc.Files {
+				if fileMatch(file) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case models.TagFilter:
+		return func(doc *document) bool {
+			for _, t := range doc.Tags {
+				if strings.ToUpper(string(t.Name)) == string(f.Name) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case models.FileFilter:
+		match := fileNameMatch(f.Name)
+		return func(doc *document) bool {
+			for _, file := range doc.Files {
+				if match(file) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case models.LangFilter:
+		return func(doc *document) bool {
+			for _, file := range doc.Files {
+				if file.Language == f.Language {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case models.UpdatedFilter:
+		return func(doc *document) bool { return compareTime(doc.UpdatedAt, f.Op, f.Time) }, nil
+	case models.CreatedFilter:
+		return func(doc *document) bool { return compareTime(doc.CreatedAt, f.Op, f.Time) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported filter %T", f)
+	}
+}
+
+func (ix *invertedIndex) compileAll(filters []models.Filter) ([]func(*document) bool, error) {
+	preds := make([]func(*document) bool, len(filters))
+	for i, child := range filters {
+		pred, err := ix.compile(child)
+		if err != nil {
+			return nil, err
+		}
+		preds[i] = pred
+	}
+	return preds, nil
+}
+
+// fileTextMatch matches the code files holding every term of a text, or of
+// any of its alternatives, in their name or content, or the whole phrase in
+// their content. As a fallback, the file name is matched against the text by
+// trigram similarity.
+func fileTextMatch(f models.TextFilter) func(models.CodeFile) bool {
+	var terms [][]string
+	var values []string
+	for _, v := range f.Values() {
+		terms, values = append(terms, tokenize(v)), append(values, strings.ToLower(v))
+	}
+	return func(file models.CodeFile) bool {
+		var found map[string]struct{}
+		for i, value := range values {
+			if nameMatch(file.Name, value) {
+				return true
+			}
+			if f.Phrase {
+				if containsFold(file.Content, value) {
+					return true
+				}
+				continue
+			}
+			if len(terms[i]) == 0 {
+				continue
+			}
+			if found == nil {
+				found = make(map[string]struct{})
+				for _, term := range tokenize(file.Name + "\n" + file.Content) {
+					found[term] = struct{}{}
+				}
+			}
+			if hasAll(found, terms[i]) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func hasAll(found map[string]struct{}, terms []string) bool {
+	for _, term := range terms {
+		if _, ok := found[term]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// fileNameMatch matches the code file names against a name with optional '*'
+// wildcards, ignoring case.
+func fileNameMatch(name string) func(models.CodeFile) bool {
+	parts := strings.Split(name, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	re := regexp.MustCompile("(?is)^" + strings.Join(parts, ".*") + "$")
+	return func(file models.CodeFile) bool { return re.MatchString(file.Name) }
+}
+
+func compareTime(t time.Time, op models.CompareOp, other time.Time) bool {
+	switch op {
+	case models.CompareEq:
+		y1, m1, d1 := t.UTC().Date()
+		y2, m2, d2 := other.UTC().Date()
+		return y1 == y2 && m1 == m2 && d1 == d2
+	case models.CompareGt:
+		return t.After(other)
+	case models.CompareGte:
+		return !t.Before(other)
+	case models.CompareLt:
+		return t.Before(other)
+	case models.CompareLte:
+		return !t.After(other)
+	}
+	return false
+}
+
+// containsFold tells whether a text contains a lower cased value, ignoring case.
+func containsFold(text, value string) bool {
+	return value != "" && strings.Contains(strings.ToLower(text), value)
+}
@@ -0,0 +1,124 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+const (
+	// SnippetContextLines is the number of lines shown before and after a matched line.
+	SnippetContextLines = 2
+	// MaximumSnippets is the maximum number of snippets extracted from a single file.
+	MaximumSnippets = 3
+)
+
+type snippetWindow struct {
+	start, end int
+	matches    []int
+}
+
+// Snippets extracts the snippets of a code file where any of the terms occur,
+// ignoring case. Matches whose context lines overlap are merged into the same snippet.
+func Snippets(fileId int, content string, terms []string) []models.Snippet {
+	lines := strings.Split(content, "\n")
+	ranges := make(map[int][]models.Highlight)
+	var windows []snippetWindow
+	for i, line := range lines {
+		found := Find(line, terms)
+		if len(found) == 0 {
+			continue
+		}
+		start, end := max(0, i-SnippetContextLines), min(len(lines)-1, i+SnippetContextLines)
+		if n := len(windows); n > 0 && start <= windows[n-1].end+1 {
+			windows[n-1].end = end
+		} else if n == MaximumSnippets {
+			break
+		} else {
+			windows = append(windows, snippetWindow{start: start, end: end})
+		}
+		windows[len(windows)-1].matches = append(windows[len(windows)-1].matches, i)
+		ranges[i] = found
+	}
+
+	res := make([]models.Snippet, len(windows))
+	for i, w := range windows {
+		offsets := make(map[int]int, w.end-w.start+1)
+		offset := 0
+		for l := w.start; l <= w.end; l++ {
+			offsets[l] = offset
+			offset += utf8.RuneCountInString(lines[l]) + 1
+		}
+		res[i] = models.Snippet{
+			FileId:    fileId,
+			Line:      w.matches[0] + 1,
+			StartLine: w.start + 1,
+			Text:      strings.Join(lines[w.start:w.end+1], "\n"),
+		}
+		for _, l := range w.matches {
+			for _, r := range ranges[l] {
+				res[i].Highlights = append(res[i].Highlights, models.Highlight{
+					Start: offsets[l] + r.Start,
+					End:   offsets[l] + r.End,
+				})
+			}
+		}
+	}
+	return res
+}
+
+// Find returns the ranges, in characters, of every occurrence of the terms in a line, ignoring case.
+func Find(line string, terms []string) []models.Highlight {
+	var res []models.Highlight
+	text := lowerRunes(line)
+	for _, term := range terms {
+		t := lowerRunes(term)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(text); i++ {
+			if string(text[i:i+len(t)]) == string(t) {
+				res = append(res, models.Highlight{Start: i, End: i + len(t)})
+				i += len(t) - 1
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Start < res[j].Start })
+	return res
+}
+
+// Terms splits the free text of a query into the terms to highlight. Phrases are kept whole.
+func Terms(text string, phrase bool) []string {
+	if phrase {
+		return []string{text}
+	}
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
+
+// lowerRunes lower cases a string rune by rune, so its length in characters is kept.
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MinTrigramLength is the minimum text length from which trigrams are
+	// meaningful. Shorter texts are matched as substrings instead.
+	MinTrigramLength = 3
+	// SimilarityThreshold is the similarity from which two texts match, as
+	// the default threshold of the pg_trgm % operator.
+	SimilarityThreshold = 0.3
+)
+
+// trigrams returns the set of trigrams of a text, the way pg_trgm builds them:
+// each word is lower cased and padded with two spaces before and one after.
+func trigrams(text string) map[string]struct{} {
+	res := make(map[string]struct{})
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			res[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return res
+}
+
+// Similarity returns the trigram similarity of two texts, from 0 to 1, as
+// the pg_trgm similarity function does.
+func Similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	shared := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			shared++
+		}
+	}
+	union := len(ta) + len(tb) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// nameMatch matches a name against some text by trigram similarity, unless
+// the text is too short to build trigrams from, in which case it is looked
+// for within the name, ignoring case.
+func nameMatch(name, text string) bool {
+	if utf8.RuneCountInString(text) >= MinTrigramLength {
+		return Similarity(name, text) >= SimilarityThreshold
+	}
+	return containsFold(name, text)
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// maximumSearchLogEntries bounds the search log. The oldest entries beyond it
+// are deleted by TrimSearchLog.
+const maximumSearchLogEntries = 100000
+
+type queryStatRow struct {
+	Kind           string    `boil:"kind"`
+	Query          string    `boil:"query"`
+	Filters        string    `boil:"filters"`
+	Count          int       `boil:"count"`
+	AvgResults     float64   `boil:"avg_results"`
+	LastSearchedAt time.Time `boil:"last_searched_at"`
+}
+
+type latencyRow struct {
+	Count    int     `boil:"count"`
+	Errors   int     `boil:"errors"`
+	Timeouts int     `boil:"timeouts"`
+	P50      float64 `boil:"p50"`
+	P90      float64 `boil:"p90"`
+	P95      float64 `boil:"p95"`
+	P99      float64 `boil:"p99"`
+	Max      float64 `boil:"max"`
+}
+
+// LogSearch records a search invocation in the search log.
+func (pr *projectsRepo) LogSearch(ctx context.Context, entry models.SearchLogEntry) error {
+	log := pr.l.WithPrefix("logSearch")
+
+	if _, err := pr.db.ExecContext(ctx, `
+		INSERT INTO search_log (kind, query, filters, results, latency_ms, outcome)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		entry.Kind, entry.Query, entry.Filters, entry.Results, float64(entry.Latency)/float64(time.Millisecond), entry.Outcome,
+	); err != nil {
+		log.Error("inserting search log entry", err)
+		return err
+	}
+	return nil
+}
+
+// TrimSearchLog deletes the oldest entries of the search log beyond its maximum size.
+func (pr *projectsRepo) TrimSearchLog(ctx context.Context) error {
+	log := pr.l.WithPrefix("trimSearchLog")
+
+	if _, err := pr.db.ExecContext(ctx, `
+		DELETE FROM search_log
+		WHERE id <= (SELECT MAX(id) FROM search_log) - $1`,
+		maximumSearchLogEntries,
+	); err != nil {
+		log.Error("trimming search log", err)
+		return err
+	}
+	return nil
+}
+
+// TopQueries fetches the most searched queries within the window.
+func (pr *projectsRepo) TopQueries(ctx context.Context, qp models.AnalyticsQP) (models.QueryStats, error) {
+	log := pr.l.WithPrefix("topQueries")
+
+	stats, err := pr.queryStats(ctx, qp, false)
+	if err != nil {
+		log.Error("querying top queries", err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ZeroResultQueries fetches the most searched queries within the window that completed and found nothing.
+func (pr *projectsRepo) ZeroResultQueries(ctx context.Context, qp models.AnalyticsQP) (models.QueryStats, error) {
+	log := pr.l.WithPrefix("zeroResultQueries")
+
+	stats, err := pr.queryStats(ctx, qp, true)
+	if err != nil {
+		log.Error("querying zero result queries", err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (pr *projectsRepo) queryStats(ctx context.Context, qp models.AnalyticsQP, zeroResults bool) (models.QueryStats, error) {
+	var rows []queryStatRow
+	err := queries.Raw(`
+		SELECT kind, query, filters, COUNT(*) AS count, AVG(results)::float8 AS avg_results, MAX(created_at) AS last_searched_at
+		FROM search_log
+		WHERE created_at >= NOW() - $1 * INTERVAL '1 second' AND ($2 = '' OR kind = $2) AND (NOT $3 OR (results = 0 AND outcome = 'ok'))
+		GROUP BY kind, query, filters
+		ORDER BY count DESC, last_searched_at DESC
+		LIMIT $4`,
+		qp.Window.Seconds(), string(qp.Kind), zeroResults, qp.Limit,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(models.QueryStats, len(rows))
+	for i, row := range rows {
+		res[i] = models.QueryStat{
+			Kind:           models.SearchKind(row.Kind),
+			Query:          row.Query,
+			Filters:        row.Filters,
+			Count:          row.Count,
+			AvgResults:     row.AvgResults,
+			LastSearchedAt: row.LastSearchedAt.Local().Unix(),
+		}
+	}
+	return res, nil
+}
+
+// SearchLatency computes the latency percentiles of the searches within the window.
+func (pr *projectsRepo) SearchLatency(ctx context.Context, qp models.AnalyticsQP) (models.LatencyStats, error) {
+	log := pr.l.WithPrefix("searchLatency")
+
+	var row latencyRow
+	err := queries.Raw(`
+		SELECT COUNT(*) AS count,
+			COUNT(*) FILTER (WHERE outcome = 'error') AS errors,
+			COUNT(*) FILTER (WHERE outcome = 'timeout') AS timeouts,
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY latency_ms), 0) AS p50,
+			COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY latency_ms), 0) AS p90,
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms), 0) AS p95,
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY latency_ms), 0) AS p99,
+			COALESCE(MAX(latency_ms), 0) AS max
+		FROM search_log
+		WHERE created_at >= NOW() - $1 * INTERVAL '1 second' AND ($2 = '' OR kind = $2)`,
+		qp.Window.Seconds(), string(qp.Kind),
+	).Bind(ctx, pr.db, &row)
+	if err != nil {
+		log.Error("querying search latency", err)
+		return models.LatencyStats{}, err
+	}
+	return models.LatencyStats{Count: row.Count, Errors: row.Errors, Timeouts: row.Timeouts, P50: row.P50, P90: row.P90, P95: row.P95, P99: row.P99, Max: row.Max}, nil
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"lastimplementation.com/pkg/services/projects"
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+type documentRow struct {
+	ID          int       `boil:"id"`
+	Name        string    `boil:"name"`
+	Description string    `boil:"description"`
+	CreatedAt   time.Time `boil:"created_at"`
+	UpdatedAt   time.Time `boil:"updated_at"`
+}
+
+type documentFileRow struct {
+	ProjectID int    `boil:"project_id"`
+	ID        int    `boil:"id"`
+	Name      string `boil:"name"`
+	Content   string `boil:"content"`
+	Language  string `boil:"language"`
+}
+
+type documentTagRow struct {
+	ProjectID int    `boil:"project_id"`
+	ID        int    `boil:"id"`
+	Name      string `boil:"name"`
+}
+
+// GetSearchDocuments fetches every project, along with its tags and code
+// files, to build a search index from.
+func (pr *projectsRepo) GetSearchDocuments(ctx context.Context) ([]models.SearchDocument, error) {
+	log := pr.l.WithPrefix("getSearchDocuments")
+
+	docs, err := pr.searchDocuments(ctx, "TRUE")
+	if err != nil {
+		log.Error("getting search documents", err)
+		return nil, err
+	}
+	return docs, nil
+}
+
+// GetSearchDocument fetches a project, along with its tags and code files, to index it.
+func (pr *projectsRepo) GetSearchDocument(ctx context.Context, id int) (models.SearchDocument, error) {
+	log := pr.l.WithPrefix("getSearchDocument")
+
+	docs, err := pr.searchDocuments(ctx, "p.id = $1", id)
+	if err != nil {
+		log.Error("getting search document", err)
+		return models.SearchDocument{}, err
+	}
+	if len(docs) == 0 {
+		return models.SearchDocument{}, projects.ErrProjectNotFound
+	}
+	return docs[0], nil
+}
+
+// searchDocuments fetches the projects matching a predicate over the projects
+// table, aliased as p, along with their tags and code files. The three
+// queries run in a single read-only snapshot so that a project updated
+// meanwhile is never indexed with files or tags of another version.
+func (pr *projectsRepo) searchDocuments(ctx context.Context, where string, args ...interface{}) ([]models.SearchDocument, error) {
+	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+
+	var rows []documentRow
+	if err := queries.Raw(fmt.Sprintf(
+		"SELECT p.id, p.name, p.description, p.created_at, p.updated_at FROM projects p WHERE %s ORDER BY p.id", where),
+		args...,
+	).Bind(ctx, tx, &rows); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("querying projects: %w", err)
+	}
+	var files []documentFileRow
+	if err := queries.Raw(fmt.Sprintf(`
+		SELECT cf.project_id, cf.id, cf.name, cf.content, cf.language
+		FROM code_files cf
+		INNER JOIN projects p ON p.id = cf.project_id
+		WHERE %s
+		ORDER BY cf.created_at, cf.id`, where),
+		args...,
+	).Bind(ctx, tx, &files); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("querying code files: %w", err)
+	}
+	var tags []documentTagRow
+	if err := queries.Raw(fmt.Sprintf(`
+		SELECT pt.project_id, t.id, t.name
+		FROM projects_tags pt
+		INNER JOIN tags t ON t.id = pt.tag_id
+		INNER JOIN projects p ON p.id = pt.project_id
+		WHERE %s
+		ORDER BY pt.id`, where),
+		args...,
+	).Bind(ctx, tx, &tags); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("querying tags: %w", err)
+	}
+	tx.Commit()
+
+	res := make([]models.SearchDocument, len(rows))
+	docs := make(map[int]*models.SearchDocument, len(rows))
+	for i, row := range rows {
+		res[i] = models.SearchDocument{
+			Id:          row.ID,
+			Name:        row.Name,
+			Description: row.Description,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			Tags:        []models.Tag{},
+			Files:       []models.CodeFile{},
+		}
+		docs[row.ID] = &res[i]
+	}
+	for _, f := range files {
+		if doc, ok := docs[f.ProjectID]; ok {
+			doc.Files = append(doc.Files, models.CodeFile{Id: f.ID, Name: f.Name, Content: f.Content, Language: f.Language})
+		}
+	}
+	for _, t := range tags {
+		if doc, ok := docs[t.ProjectID]; ok {
+			doc.Tags = append(doc.Tags, models.Tag{Id: t.ID, Name: models.TagType(t.Name)})
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"lastimplementation.com/pkg/services/projects"
+	"lastimplementation.com/pkg/services/projects/fingerprint"
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// snippetsPerReport is the number of duplicated fingerprints looked at for
+// every snippet of the report, since neighbouring fingerprints of the same
+// duplicated code collapse into a single snippet.
+const snippetsPerReport = 5
+
+type duplicateFileRow struct {
+	ProjectID   int     `boil:"project_id"`
+	ProjectName string  `boil:"project_name"`
+	FileID      int     `boil:"file_id"`
+	FileName    string  `boil:"file_name"`
+	Similarity  float64 `boil:"similarity"`
+}
+
+type duplicatedHashRow struct {
+	Hash      int64  `boil:"hash"`
+	Files     int    `boil:"files"`
+	Projects  int    `boil:"projects"`
+	ProjectID int    `boil:"project_id"`
+	FileID    int    `boil:"file_id"`
+	FileName  string `boil:"file_name"`
+	Line      int    `boil:"line"`
+	Text      string `boil:"text"`
+}
+
+// indexFingerprints rebuilds the fingerprints of the code files of a project.
+func (pr *projectsRepo) indexFingerprints(ctx context.Context, tx *sql.Tx, projectId int, files []indexedFile) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM code_fingerprints WHERE project_id = $1", projectId); err != nil {
+		return fmt.Errorf("deleting project fingerprints: %w", err)
+	}
+
+	for _, f := range files {
+		fps := fingerprint.Winnow(f.Content)
+		if len(fps) == 0 {
+			continue
+		}
+		hashes, lines := make([]int64, len(fps)), make([]int64, len(fps))
+		for i, fp := range fps {
+			hashes[i], lines[i] = fp.Hash, int64(fp.Line)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO code_fingerprints (project_id, file_id, hash, line)
+			SELECT $1, $2, UNNEST($3::bigint[]), UNNEST($4::int[])`,
+			projectId, f.ID, pq.Array(hashes), pq.Array(lines),
+		); err != nil {
+			return fmt.Errorf("inserting file %d fingerprints: %w", f.ID, err)
+		}
+	}
+	return nil
+}
+
+// FindDuplicates fetches the code files of other projects sharing most of
+// their fingerprints with a code file, by their Jaccard similarity.
+func (pr *projectsRepo) FindDuplicates(ctx context.Context, projectId, fileId int, qp models.DuplicatesQP) (models.DuplicateFiles, error) {
+	log := pr.l.WithPrefix("findDuplicates")
+
+	var exists bool
+	if err := pr.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM code_files WHERE id = $1 AND project_id = $2)", fileId, projectId,
+	).Scan(&exists); err != nil {
+		log.Error("finding code file", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, projects.ErrCodeFileNotFound
+	}
+
+	var rows []duplicateFileRow
+	err := queries.Raw(`
+		WITH target AS (
+			SELECT DISTINCT hash FROM code_fingerprints WHERE file_id = $1
+		), shared AS (
+			SELECT f.file_id, COUNT(DISTINCT f.hash) AS shared
+			FROM code_fingerprints f
+			INNER JOIN target t ON t.hash = f.hash
+			WHERE f.project_id <> $2
+			GROUP BY f.file_id
+		), sizes AS (
+			SELECT file_id, COUNT(DISTINCT hash) AS size
+			FROM code_fingerprints
+			WHERE file_id IN (SELECT file_id FROM shared)
+			GROUP BY file_id
+		)
+		SELECT * FROM (
+			SELECT cf.project_id, p.name AS project_name, cf.id AS file_id, cf.name AS file_name,
+				s.shared::float / ((SELECT COUNT(*) FROM target) + z.size - s.shared) AS similarity
+			FROM shared s
+			INNER JOIN sizes z ON z.file_id = s.file_id
+			INNER JOIN code_files cf ON cf.id = s.file_id
+			INNER JOIN projects p ON p.id = cf.project_id
+		) d
+		WHERE d.similarity >= $3
+		ORDER BY d.similarity DESC, d.project_id, d.file_id
+		LIMIT $4`,
+		fileId, projectId, qp.MinSimilarity, qp.Limit,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		log.Error("querying duplicate files", err)
+		return nil, err
+	}
+
+	res := make(models.DuplicateFiles, len(rows))
+	for i, row := range rows {
+		res[i] = models.DuplicateFile{
+			ProjectId:   row.ProjectID,
+			ProjectName: row.ProjectName,
+			FileId:      row.FileID,
+			FileName:    row.FileName,
+			Similarity:  row.Similarity,
+		}
+	}
+	return res, nil
+}
+
+// FindDuplicatedSnippets reports the snippets found in the most code files.
+// Each snippet is the line where a duplicated fingerprint starts, and the
+// fingerprints starting at the same line text are reported once.
+func (pr *projectsRepo) FindDuplicatedSnippets(ctx context.Context, qp models.DuplicatesQP) (models.DuplicatedSnippets, error) {
+	log := pr.l.WithPrefix("findDuplicatedSnippets")
+
+	var rows []duplicatedHashRow
+	err := queries.Raw(`
+		WITH duplicated AS (
+			SELECT hash, COUNT(DISTINCT file_id) AS files, COUNT(DISTINCT project_id) AS projects
+			FROM code_fingerprints
+			GROUP BY hash
+			HAVING COUNT(DISTINCT file_id) > 1
+			ORDER BY files DESC, projects DESC, hash
+			LIMIT $1
+		)
+		SELECT DISTINCT ON (d.files, d.projects, d.hash, f.project_id, f.file_id)
+			d.hash, d.files, d.projects, f.project_id, f.file_id, cf.name AS file_name, f.line,
+			split_part(cf.content, E'\n', f.line) AS text
+		FROM duplicated d
+		INNER JOIN code_fingerprints f ON f.hash = d.hash
+		INNER JOIN code_files cf ON cf.id = f.file_id
+		ORDER BY d.files DESC, d.projects DESC, d.hash, f.project_id, f.file_id, f.line`,
+		qp.Limit*snippetsPerReport,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		log.Error("querying duplicated snippets", err)
+		return nil, err
+	}
+
+	res := make(models.DuplicatedSnippets, 0, qp.Limit)
+	seen := make(map[string]struct{})
+	for i := 0; i < len(rows) && len(res) < qp.Limit; {
+		hash, text := rows[i].Hash, strings.TrimSpace(rows[i].Text)
+		snippet := models.DuplicatedSnippet{Text: text, Files: rows[i].Files, Projects: rows[i].Projects}
+		for ; i < len(rows) && rows[i].Hash == hash; i++ {
+			snippet.Occurrences = append(snippet.Occurrences, models.SnippetOccurrence{
+				ProjectId: rows[i].ProjectID,
+				FileId:    rows[i].FileID,
+				FileName:  rows[i].FileName,
+				Line:      rows[i].Line,
+			})
+		}
+		if _, ok := seen[text]; ok {
+			continue
+		}
+		seen[text] = struct{}{}
+		res = append(res, snippet)
+	}
+	return res, nil
+}
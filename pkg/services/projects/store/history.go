@@ -0,0 +1,533 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+	"lastimplementation.com/pkg/services/projects"
+	"lastimplementation.com/pkg/services/projects/diff"
+	"lastimplementation.com/pkg/services/projects/language"
+	"lastimplementation.com/pkg/services/projects/models"
+	"lastimplementation.com/pkg/services/projects/store/dao"
+)
+
+// snapshotFiles records a new revision of a project, holding a copy of its
+// current code files. It runs after the files are changed, and when the
+// project is added, so each revision holds the files as they were left by
+// that change and the latest revision always matches the current files. This
+// lets the symbols, diffs, restores and blames read any state of the files,
+// the current one included, from the revisions alone. The revisions written
+// before held the files as they were before each change, and Migrate gives
+// them this meaning.
+func (pr *projectsRepo) snapshotFiles(ctx context.Context, tx *sql.Tx, projectId int) error {
+	dbHistProj := dao.ProjectsHistory{ProjectID: projectId}
+	if err := dbHistProj.Insert(ctx, tx, boil.Infer()); err != nil {
+		return fmt.Errorf("inserting project revision history: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO projects_code_files_history (name, content, revision_id, file_id)
+		SELECT name, content, $1, id FROM code_files WHERE project_id = $2 ORDER BY created_at, id`,
+		dbHistProj.ID, projectId,
+	); err != nil {
+		return fmt.Errorf("inserting code files to history: %w", err)
+	}
+	return nil
+}
+
+// GetFileRevisions fetches the revisions holding each of the given code files,
+// from newest to oldest, up to limit revisions of their projects. Each file
+// is followed back from the newest revision, matched in every revision by its
+// id or, failing that, by the name it had in the revision after, until a
+// revision lacks it.
+func (pr *projectsRepo) GetFileRevisions(ctx context.Context, files []models.FileRef, limit int) ([][]models.FileRevision, error) {
+	log := pr.l.WithPrefix("getFileRevisions")
+
+	res := make([][]models.FileRevision, len(files))
+	if len(files) == 0 {
+		return res, nil
+	}
+	projectIds := make([]int64, len(files))
+	for i, f := range files {
+		projectIds[i] = int64(f.ProjectId)
+	}
+
+	var rows []historyFileRow
+	err := queries.Raw(`
+		SELECT h.id, ph.project_id, ph.revision_number, ph.created_at, COALESCE(h.file_id, 0) AS file_id, h.name
+		FROM (
+			SELECT id, project_id, revision_number, created_at,
+				ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY revision_number DESC) AS n
+			FROM projects_history
+			WHERE project_id = ANY($1)
+		) ph
+		INNER JOIN projects_code_files_history h ON h.revision_id = ph.id
+		WHERE ph.n <= $2
+		ORDER BY ph.project_id, ph.revision_number DESC, h.id`,
+		pq.Array(projectIds), limit,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		log.Error("querying revision files", err)
+		return nil, err
+	}
+	byProject := make(map[int][]historyFileRow)
+	for _, row := range rows {
+		byProject[row.ProjectID] = append(byProject[row.ProjectID], row)
+	}
+
+	traces := make([][]historyFileRow, len(files))
+	var ids []int64
+	for i, f := range files {
+		traces[i] = traceFile(byProject[f.ProjectId], f.FileId, f.Name)
+		for _, row := range traces[i] {
+			ids = append(ids, int64(row.ID))
+		}
+	}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	var contents []historyContentRow
+	err = queries.Raw(
+		"SELECT id, content FROM projects_code_files_history WHERE id = ANY($1)", pq.Array(ids),
+	).Bind(ctx, pr.db, &contents)
+	if err != nil {
+		log.Error("querying revision file contents", err)
+		return nil, err
+	}
+	byId := make(map[int]string, len(contents))
+	for _, c := range contents {
+		byId[c.ID] = c.Content
+	}
+
+	for i, trace := range traces {
+		res[i] = make([]models.FileRevision, len(trace))
+		for j, row := range trace {
+			res[i][j] = models.FileRevision{
+				RevisionRef: models.RevisionRef{Number: row.RevisionNumber, CreatedAt: row.CreatedAt.Local().Unix()},
+				Name:        row.Name,
+				Content:     byId[row.ID],
+			}
+		}
+	}
+	return res, nil
+}
+
+// traceFile follows a code file back through the files of the revisions of
+// its project, ordered from the newest revision.
+func traceFile(rows []historyFileRow, fileId int, name string) []historyFileRow {
+	var res []historyFileRow
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && rows[end].RevisionNumber == rows[start].RevisionNumber {
+			end++
+		}
+		match := -1
+		for i := start; i < end; i++ {
+			if fileId != 0 && rows[i].FileID == fileId {
+				match = i
+				break
+			}
+			if match == -1 && rows[i].Name == name {
+				match = i
+			}
+		}
+		if match == -1 {
+			break
+		}
+		res = append(res, rows[match])
+		if rows[match].FileID != 0 {
+			fileId = rows[match].FileID
+		}
+		name = rows[match].Name
+		start = end
+	}
+	return res
+}
+
+// ScanFileRevisions walks through the code files recorded in every revision,
+// ordered by project, file and revision, until fn returns an error.
+func (pr *projectsRepo) ScanFileRevisions(ctx context.Context, fn func(models.ProjectFileRevision) error) error {
+	log := pr.l.WithPrefix("scanFileRevisions")
+
+	rows, err := pr.db.QueryContext(ctx, `
+		SELECT p.id, p.name, COALESCE(h.file_id, 0), h.name, h.content, ph.revision_number, ph.created_at,
+			ph.latest_revision, COALESCE(ph.next_revision, 0)
+		FROM projects_code_files_history h
+		INNER JOIN (
+			SELECT id, project_id, revision_number, created_at,
+				MAX(revision_number) OVER (PARTITION BY project_id) AS latest_revision,
+				LEAD(revision_number) OVER (PARTITION BY project_id ORDER BY revision_number) AS next_revision
+			FROM projects_history
+		) ph ON ph.id = h.revision_id
+		INNER JOIN projects p ON p.id = ph.project_id
+		ORDER BY p.id, COALESCE(h.file_id, 0), CASE WHEN h.file_id IS NULL THEN h.name END, ph.revision_number`)
+	if err != nil {
+		log.Error("querying code file revisions", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f models.ProjectFileRevision
+		var createdAt time.Time
+		if err := rows.Scan(&f.ProjectId, &f.ProjectName, &f.FileId, &f.Name, &f.Content, &f.Number, &createdAt, &f.LatestRevision, &f.NextRevision); err != nil {
+			log.Error("reading code file revision", err)
+			return err
+		}
+		f.CreatedAt = createdAt.Local().Unix()
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+type revisionRow struct {
+	ID             int       `boil:"id"`
+	RevisionNumber int       `boil:"revision_number"`
+	CreatedAt      time.Time `boil:"created_at"`
+	PrevID         int       `boil:"prev_id"`
+}
+
+type revisionFileRow struct {
+	ID         int    `boil:"id"`
+	RevisionID int    `boil:"revision_id"`
+	FileID     int    `boil:"file_id"`
+	Name       string `boil:"name"`
+	Digest     string `boil:"digest"`
+}
+
+// GetRevisions fetches a page of the revisions of a project, from newest to
+// oldest, comparing each one with its previous revision.
+func (pr *projectsRepo) GetRevisions(ctx context.Context, projectId int, qp models.RevisionsQP) (models.RevisionsList, error) {
+	log := pr.l.WithPrefix("getRevisions")
+
+	var res models.RevisionsList
+	exists, err := dao.ProjectExists(ctx, pr.db, projectId)
+	if err != nil {
+		log.Error("finding project", err)
+		return res, err
+	}
+	if !exists {
+		return res, projects.ErrProjectNotFound
+	}
+
+	total, err := dao.ProjectsHistories(qm.Where("project_id = ?", projectId)).Count(ctx, pr.db)
+	if err != nil {
+		log.Error("counting revisions", err)
+		return res, err
+	}
+
+	var rows []revisionRow
+	err = queries.Raw(`
+		SELECT id, revision_number, created_at, prev_id FROM (
+			SELECT ph.id, ph.revision_number, ph.created_at,
+				COALESCE(LAG(ph.id) OVER (ORDER BY ph.revision_number), 0) AS prev_id
+			FROM projects_history ph
+			WHERE ph.project_id = $1
+		) r
+		ORDER BY revision_number DESC
+		LIMIT $2 OFFSET $3`,
+		projectId, qp.Limit, (qp.Page-1)*qp.Limit,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		log.Error("querying revisions", err)
+		return res, err
+	}
+
+	var revisionIds []int64
+	for _, row := range rows {
+		revisionIds = append(revisionIds, int64(row.ID))
+		if row.PrevID != 0 {
+			revisionIds = append(revisionIds, int64(row.PrevID))
+		}
+	}
+	var fileRows []revisionFileRow
+	if len(revisionIds) > 0 {
+		err = queries.Raw(`
+			SELECT id, revision_id, COALESCE(file_id, 0) AS file_id, name, MD5(content) AS digest
+			FROM projects_code_files_history
+			WHERE revision_id = ANY($1)
+			ORDER BY id`,
+			pq.Array(revisionIds),
+		).Bind(ctx, pr.db, &fileRows)
+		if err != nil {
+			log.Error("querying revision files", err)
+			return res, err
+		}
+	}
+	files := make(map[int][]revisionFileRow)
+	for _, f := range fileRows {
+		files[f.RevisionID] = append(files[f.RevisionID], f)
+	}
+
+	changes, err := pr.revisionChanges(ctx, rows, files)
+	if err != nil {
+		log.Error("pairing revision files", err)
+		return res, err
+	}
+
+	res.Data = make([]models.Revision, len(rows))
+	for i, row := range rows {
+		rev := changes[i]
+		rev.RevisionRef = models.RevisionRef{Number: row.RevisionNumber, CreatedAt: row.CreatedAt.Local().Unix()}
+		rev.FileCount = len(files[row.ID])
+		res.Data[i] = rev
+	}
+	res.TotalItems = int(total)
+	res.TotalPages = int(math.Ceil(float64(total) / float64(qp.Limit)))
+	res.Count = len(res.Data)
+	res.Page = qp.Page
+	return res, nil
+}
+
+// revisionChanges compares the code files of each revision with the ones of
+// its previous revision, the way diff.Files pairs them. The contents are only
+// fetched for the files left added and removed, in a single query, to find the
+// renamed ones among them.
+func (pr *projectsRepo) revisionChanges(ctx context.Context, rows []revisionRow, files map[int][]revisionFileRow) ([]models.Revision, error) {
+	codeFiles := func(rows []revisionFileRow) models.CodeFiles {
+		res := make(models.CodeFiles, len(rows))
+		for i, f := range rows {
+			res[i] = models.CodeFile{Id: f.FileID, Name: f.Name}
+		}
+		return res
+	}
+	pairs := make([][]diff.Pair, len(rows))
+	var unpaired []int64
+	for i, row := range rows {
+		prev, cur := files[row.PrevID], files[row.ID]
+		pairs[i] = diff.PairFiles(codeFiles(prev), codeFiles(cur))
+		var added, removed []int64
+		for _, p := range pairs[i] {
+			if p.From == -1 {
+				added = append(added, int64(cur[p.To].ID))
+			} else if p.To == -1 {
+				removed = append(removed, int64(prev[p.From].ID))
+			}
+		}
+		if len(added) > 0 && len(removed) > 0 {
+			unpaired = append(append(unpaired, added...), removed...)
+		}
+	}
+
+	contents := make(map[int]string)
+	if len(unpaired) > 0 {
+		var contentRows []historyContentRow
+		if err := queries.Raw(`
+			SELECT id, content FROM projects_code_files_history WHERE id = ANY($1)`,
+			pq.Array(unpaired),
+		).Bind(ctx, pr.db, &contentRows); err != nil {
+			return nil, err
+		}
+		for _, row := range contentRows {
+			contents[row.ID] = row.Content
+		}
+	}
+
+	res := make([]models.Revision, len(rows))
+	for i, row := range rows {
+		prev, cur := files[row.PrevID], files[row.ID]
+		from, to := codeFiles(prev), codeFiles(cur)
+		for j, f := range prev {
+			from[j].Content = contents[f.ID]
+		}
+		for j, f := range cur {
+			to[j].Content = contents[f.ID]
+		}
+		rev := models.Revision{Added: []string{}, Changed: []string{}, Removed: []string{}, Renamed: []models.FileRename{}}
+		for _, p := range diff.PairRenames(from, to, pairs[i]) {
+			switch {
+			case p.From == -1:
+				rev.Added = append(rev.Added, cur[p.To].Name)
+			case p.To == -1:
+				rev.Removed = append(rev.Removed, prev[p.From].Name)
+			case prev[p.From].Name != cur[p.To].Name:
+				rev.Renamed = append(rev.Renamed, models.FileRename{From: prev[p.From].Name, To: cur[p.To].Name})
+			case prev[p.From].Digest != cur[p.To].Digest:
+				rev.Changed = append(rev.Changed, cur[p.To].Name)
+			}
+		}
+		res[i] = rev
+	}
+	return res, nil
+}
+
+type revisionCodeFileRow struct {
+	FileID  int    `boil:"file_id"`
+	Name    string `boil:"name"`
+	Content string `boil:"content"`
+}
+
+// GetRevisionFiles fetches the code files of a project as they were recorded
+// in a revision. Files recorded without their id have none.
+func (pr *projectsRepo) GetRevisionFiles(ctx context.Context, projectId, revision int) (models.CodeFiles, error) {
+	log := pr.l.WithPrefix("getRevisionFiles")
+
+	files, err := pr.revisionFiles(ctx, pr.db, projectId, revision)
+	if err != nil {
+		log.Error("fetching revision files", err)
+		return nil, err
+	}
+	return files, nil
+}
+
+func (pr *projectsRepo) revisionFiles(ctx context.Context, exec boil.ContextExecutor, projectId, revision int) (models.CodeFiles, error) {
+	dbRevision, err := dao.ProjectsHistories(
+		qm.Select(dao.ProjectsHistoryColumns.ID),
+		qm.Where("project_id = ? AND revision_number = ?", projectId, revision),
+	).One(ctx, exec)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), ErrNotResult()) {
+			return nil, projects.ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("finding revision: %w", err)
+	}
+
+	var rows []revisionCodeFileRow
+	err = queries.Raw(`
+		SELECT COALESCE(file_id, 0) AS file_id, name, content
+		FROM projects_code_files_history
+		WHERE revision_id = $1
+		ORDER BY id`,
+		dbRevision.ID,
+	).Bind(ctx, exec, &rows)
+	if err != nil {
+		return nil, fmt.Errorf("querying revision files: %w", err)
+	}
+
+	files := make(models.CodeFiles, len(rows))
+	for i, row := range rows {
+		files[i] = models.CodeFile{
+			Id:       row.FileID,
+			Name:     row.Name,
+			Content:  row.Content,
+			Language: language.Detect(row.Name, row.Content),
+		}
+	}
+	return files, nil
+}
+
+// RestoreRevision brings back the code files of a project as they were in a
+// revision, recording the result as a new revision. When names are given,
+// only the files with those names are restored and the rest are kept.
+func (pr *projectsRepo) RestoreRevision(ctx context.Context, projectId, revision int, names []string) error {
+	log := pr.l.WithPrefix("restoreRevision")
+
+	tx, err := pr.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("begining transaction", err)
+		return err
+	}
+
+	files, err := pr.restoredFiles(ctx, tx, projectId, revision, names)
+	if err != nil {
+		log.Error("reading restored files", err)
+		tx.Rollback()
+		return err
+	}
+
+	if err := pr.updateFiles(ctx, tx, projectId, files); err != nil {
+		log.Error("updating project files", err)
+		tx.Rollback()
+		return err
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// restoredFiles builds the code files of a project once a revision is
+// restored. The files keep their ids while they still exist, so they are
+// updated in place rather than replaced.
+func (pr *projectsRepo) restoredFiles(ctx context.Context, tx *sql.Tx, projectId, revision int, names []string) (models.CodeFiles, error) {
+	revFiles, err := pr.revisionFiles(ctx, tx, projectId, revision)
+	if err != nil {
+		return nil, err
+	}
+	dbFiles, err := dao.CodeFiles(
+		qm.Select(dao.CodeFileColumns.ID, dao.CodeFileColumns.Name, dao.CodeFileColumns.Content),
+		qm.Where("project_id = ?", projectId),
+		qm.OrderBy(dao.CodeFileColumns.CreatedAt+", "+dao.CodeFileColumns.ID),
+	).All(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("getting project files: %w", err)
+	}
+	current := make(models.CodeFiles, len(dbFiles))
+	for i, dbFile := range dbFiles {
+		current[i] = models.CodeFile{Id: dbFile.ID, Name: dbFile.Name, Content: dbFile.Content}
+	}
+
+	if len(names) == 0 {
+		exists := make(map[int]bool)
+		for _, f := range current {
+			exists[f.Id] = true
+		}
+		for i := range revFiles {
+			if !exists[revFiles[i].Id] {
+				revFiles[i].Id = 0
+			}
+		}
+		return revFiles, nil
+	}
+
+	for _, name := range names {
+		var restored *models.CodeFile
+		for i := range revFiles {
+			if revFiles[i].Name == name {
+				restored = &revFiles[i]
+				break
+			}
+		}
+		if restored == nil {
+			return nil, projects.ErrCodeFileNotFound
+		}
+		current = restoreFile(current, *restored)
+	}
+	if len(current) > models.MaximumCodeFiles {
+		return nil, projects.ErrTooManyCodeFiles
+	}
+	return current, nil
+}
+
+// restoreFile puts a file back into the current code files, over the file
+// with its id or, failing that, its name. Otherwise it is added as a new file.
+func restoreFile(current models.CodeFiles, file models.CodeFile) models.CodeFiles {
+	at := -1
+	for i, f := range current {
+		if file.Id != 0 && f.Id == file.Id {
+			at = i
+			break
+		}
+		if at == -1 && f.Name == file.Name {
+			at = i
+		}
+	}
+	if at == -1 {
+		return append(current, models.CodeFile{Name: file.Name, Content: file.Content})
+	}
+	current[at].Name, current[at].Content = file.Name, file.Content
+	return current
+}
+
+type historyFileRow struct {
+	ID             int       `boil:"id"`
+	ProjectID      int       `boil:"project_id"`
+	RevisionNumber int       `boil:"revision_number"`
+	CreatedAt      time.Time `boil:"created_at"`
+	FileID         int       `boil:"file_id"`
+	Name           string    `boil:"name"`
+}
+
+type historyContentRow struct {
+	ID      int    `boil:"id"`
+	Content string `boil:"content"`
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"lastimplementation.com/pkg/services/projects/language"
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+type indexedFile struct {
+	ID       int    `boil:"id"`
+	Name     string `boil:"name"`
+	Content  string `boil:"content"`
+	Language string `boil:"language"`
+}
+
+// indexFiles refreshes the data derived from the code files of a project: the
+// language of each file, the language tags of the project, its symbols and
+// the fingerprints of its files.
+func (pr *projectsRepo) indexFiles(ctx context.Context, tx *sql.Tx, projectId int) error {
+	var files []indexedFile
+	if err := queries.Raw(
+		"SELECT id, name, content, language FROM code_files WHERE project_id = $1 ORDER BY id", projectId,
+	).Bind(ctx, tx, &files); err != nil {
+		return fmt.Errorf("getting project files: %w", err)
+	}
+
+	langs := make(map[string]struct{})
+	for i, f := range files {
+		lang := language.Detect(f.Name, f.Content)
+		if lang != f.Language {
+			if _, err := tx.ExecContext(ctx, "UPDATE code_files SET language = $1 WHERE id = $2", lang, f.ID); err != nil {
+				return fmt.Errorf("updating file %d language: %w", f.ID, err)
+			}
+			files[i].Language = lang
+		}
+		if lang != "" {
+			langs[lang] = struct{}{}
+		}
+	}
+
+	if err := pr.syncLanguageTags(ctx, tx, projectId, langs); err != nil {
+		return err
+	}
+	if err := pr.indexSymbols(ctx, tx, projectId, files); err != nil {
+		return err
+	}
+	return pr.indexFingerprints(ctx, tx, projectId, files)
+}
+
+// syncLanguageTags makes the language tags of a project match the languages of
+// its code files. It creates the missing tags in the language category. When a
+// tag created by a user already has the name of a language, that tag is added
+// instead, keeping its category. Only the tags of the language category are
+// removed, so the tags created by users are never taken off the projects.
+func (pr *projectsRepo) syncLanguageTags(ctx context.Context, tx *sql.Tx, projectId int, langs map[string]struct{}) error {
+	names := make([]string, 0, len(langs))
+	for lang := range langs {
+		names = append(names, strings.ToUpper(lang))
+	}
+	sort.Strings(names)
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM projects_tags pt USING tags t
+		WHERE pt.tag_id = t.id AND pt.project_id = $1 AND t.category = $2 AND NOT (t.name = ANY($3))`,
+		projectId, models.TagTypeLanguage, pq.Array(names),
+	); err != nil {
+		return fmt.Errorf("removing stale language tags: %w", err)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO tags (name, category, created_at, updated_at)
+		SELECT UNNEST($1::text[]), $2, NOW(), NOW()
+		ON CONFLICT (name) DO NOTHING`,
+		pq.Array(names), models.TagTypeLanguage,
+	); err != nil {
+		return fmt.Errorf("inserting language tags: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO projects_tags (project_id, tag_id, created_at, updated_at)
+		SELECT $1, t.id, NOW(), NOW() FROM tags t
+		WHERE t.name = ANY($2)
+			AND NOT EXISTS (SELECT 1 FROM projects_tags pt WHERE pt.project_id = $1 AND pt.tag_id = t.id)`,
+		projectId, pq.Array(names),
+	); err != nil {
+		return fmt.Errorf("adding language tags to the project: %w", err)
+	}
+	return nil
+}
+
+// fileLanguages fetches the language detected for each code file of the given projects.
+func (pr *projectsRepo) fileLanguages(ctx context.Context, exec boil.ContextExecutor, projectIds []int) (map[int]string, error) {
+	var files []indexedFile
+	if err := queries.Raw(
+		"SELECT id, language FROM code_files WHERE project_id = ANY($1)", pq.Array(projectIds),
+	).Bind(ctx, exec, &files); err != nil {
+		return nil, fmt.Errorf("getting code files language: %w", err)
+	}
+	res := make(map[int]string, len(files))
+	for _, f := range files {
+		res[f.ID] = f.Language
+	}
+	return res, nil
+}
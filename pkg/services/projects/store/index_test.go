@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"lastimplementation.com/pkg/services/projects/logger"
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// testRepo returns a projects repo on the database of PROJECTS_TEST_DSN, reset
+// to its initial tables. The test is skipped when it is not set, since the
+// database is wiped.
+func testRepo(t *testing.T) *projectsRepo {
+	dsn := os.Getenv("PROJECTS_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PROJECTS_TEST_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	pr := New(logger.New("projects", false), db)
+	if err := pr.Reset(context.Background()); err != nil {
+		t.Fatalf("resetting repo: %v", err)
+	}
+	return pr
+}
+
+func TestSyncLanguageTags(t *testing.T) {
+	tests := []struct {
+		name      string
+		otherTags []models.TagType
+		tags      []models.TagType
+		files     []models.CodeFile
+		want      []string
+	}{
+		{
+			name:  "language tag created",
+			files: []models.CodeFile{{Name: "main.go", Content: "package main"}},
+			want:  []string{"GO"},
+		},
+		{
+			name:      "user tag with the name of a language",
+			otherTags: []models.TagType{"GO"},
+			files:     []models.CodeFile{{Name: "main.go", Content: "package main"}},
+			want:      []string{"GO"},
+		},
+		{
+			name: "user tag kept without files of its language",
+			tags: []models.TagType{"GO"},
+			want: []string{"GO"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pr := testRepo(t)
+			if len(tt.otherTags) > 0 {
+				_, err := pr.Add(ctx, models.Project{
+					ProjectDetails: models.ProjectDetails{Name: "user tags", Description: "Project holding the user tags"},
+					Tags:           tt.otherTags,
+				})
+				if err != nil {
+					t.Fatalf("adding user tags: %v", err)
+				}
+			}
+			id, err := pr.Add(ctx, models.Project{
+				ProjectDetails: models.ProjectDetails{Name: "languages", Description: "Project with code files"},
+				Tags:           tt.tags,
+				Files:          tt.files,
+			})
+			if err != nil {
+				t.Fatalf("adding project: %v", err)
+			}
+			p, err := pr.Get(ctx, id)
+			if err != nil {
+				t.Fatalf("getting project: %v", err)
+			}
+			got := []string{}
+			for _, tag := range p.Tags {
+				got = append(got, string(tag))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
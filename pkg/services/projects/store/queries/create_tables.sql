@@ -1,3 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE SEQUENCE projects_revision_number_seq;
 
 CREATE TABLE projects (
@@ -5,7 +7,11 @@ CREATE TABLE projects (
     name VARCHAR(200) NOT NULL UNIQUE,
     description VARCHAR(500) NOT NULL,
     created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL
+    updated_at TIMESTAMP NOT NULL,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', name), 'A') ||
+        setweight(to_tsvector('simple', description), 'B')
+    ) STORED
 );
 
 CREATE TABLE code_files (
@@ -13,14 +19,20 @@ CREATE TABLE code_files (
     project_id INT NOT NULL,
     name VARCHAR(200) NOT NULL,
     content VARCHAR(100000) NOT NULL,
+    language VARCHAR(30) NOT NULL DEFAULT '',
     created_at TIMESTAMP NOT NULL,
     updated_at TIMESTAMP NOT NULL,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', name), 'A') ||
+        setweight(to_tsvector('simple', content), 'C')
+    ) STORED,
     CONSTRAINT fk_project FOREIGN KEY(project_id) REFERENCES projects(id)
 );
 
 CREATE TABLE tags (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL UNIQUE,
+    category VARCHAR(20) NOT NULL DEFAULT 'UNKNOWN',
     created_at TIMESTAMP NOT NULL,
     updated_at TIMESTAMP NOT NULL
 );
@@ -39,6 +51,8 @@ CREATE TABLE projects_history (
     id SERIAL PRIMARY KEY,
     project_id INT NOT NULL,
     revision_number INT NOT NULL DEFAULT NEXTVAL('projects_revision_number_seq'),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    snapshot VARCHAR(10) NOT NULL DEFAULT 'after',
     CONSTRAINT fk_project FOREIGN KEY(project_id) REFERENCES projects(id)
 );
 
@@ -47,11 +61,80 @@ CREATE TABLE projects_code_files_history (
     name VARCHAR(200) NOT NULL,
     content VARCHAR(100000) NOT NULL,
     revision_id INT NOT NULL,
+    file_id INT,
     CONSTRAINT fk_revision FOREIGN KEY(revision_id) REFERENCES projects_history(id)
 );
 
+CREATE TABLE code_symbols (
+    id SERIAL PRIMARY KEY,
+    project_id INT NOT NULL,
+    file_id INT NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    receiver VARCHAR(200) NOT NULL,
+    signature VARCHAR(1000) NOT NULL,
+    digest VARCHAR(40) NOT NULL,
+    line INT NOT NULL,
+    CONSTRAINT fk_project FOREIGN KEY(project_id) REFERENCES projects(id),
+    CONSTRAINT fk_code_file FOREIGN KEY(file_id) REFERENCES code_files(id) ON DELETE CASCADE
+);
+
+CREATE TABLE code_fingerprints (
+    id SERIAL PRIMARY KEY,
+    project_id INT NOT NULL,
+    file_id INT NOT NULL,
+    hash BIGINT NOT NULL,
+    line INT NOT NULL,
+    CONSTRAINT fk_project FOREIGN KEY(project_id) REFERENCES projects(id),
+    CONSTRAINT fk_code_file FOREIGN KEY(file_id) REFERENCES code_files(id) ON DELETE CASCADE
+);
+
+CREATE TABLE saved_searches (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    query VARCHAR(1000) NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    last_seen_id INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE synonyms (
+    id SERIAL PRIMARY KEY,
+    term VARCHAR(50) NOT NULL UNIQUE,
+    synonyms TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE search_log (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(10) NOT NULL,
+    query VARCHAR(200) NOT NULL,
+    filters VARCHAR(1000) NOT NULL,
+    results INT NOT NULL,
+    latency_ms FLOAT8 NOT NULL,
+    outcome VARCHAR(10) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE INDEX project_tags_project_idx ON projects_tags(project_id);
 CREATE INDEX project_tags_tag_idx ON projects_tags(tag_id);
+CREATE INDEX projects_search_idx ON projects USING GIN(search_vector);
+CREATE INDEX code_files_search_idx ON code_files USING GIN(search_vector);
+CREATE INDEX projects_name_trgm_idx ON projects USING GIN(name gin_trgm_ops);
+CREATE INDEX code_files_name_trgm_idx ON code_files USING GIN(name gin_trgm_ops);
+CREATE INDEX projects_name_prefix_idx ON projects(LOWER(name) text_pattern_ops);
+CREATE INDEX tags_name_prefix_idx ON tags(LOWER(name) text_pattern_ops);
+CREATE INDEX code_files_name_prefix_idx ON code_files(LOWER(name) text_pattern_ops);
+CREATE INDEX code_symbols_name_idx ON code_symbols(LOWER(name) text_pattern_ops);
+CREATE INDEX code_symbols_project_idx ON code_symbols(project_id);
+CREATE INDEX code_fingerprints_hash_idx ON code_fingerprints(hash);
+CREATE INDEX code_fingerprints_file_idx ON code_fingerprints(file_id);
+CREATE INDEX code_fingerprints_project_idx ON code_fingerprints(project_id);
+CREATE INDEX search_log_created_at_idx ON search_log(created_at);
+CREATE INDEX projects_history_project_idx ON projects_history(project_id);
+CREATE INDEX projects_code_files_history_revision_idx ON projects_code_files_history(revision_id);
 
 INSERT INTO tags(name, created_at, updated_at) VALUES ('LANGUAGE', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP), ('ARCHITECTURE', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
 
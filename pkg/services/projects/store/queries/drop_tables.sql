@@ -1,3 +1,8 @@
+DROP TABLE IF EXISTS synonyms;
+DROP TABLE IF EXISTS search_log;
+DROP TABLE IF EXISTS saved_searches;
+DROP TABLE IF EXISTS code_fingerprints;
+DROP TABLE IF EXISTS code_symbols;
 DROP TABLE IF EXISTS projects_code_files_history;
 DROP TABLE IF EXISTS projects_history;
 DROP TABLE IF EXISTS projects_tags;
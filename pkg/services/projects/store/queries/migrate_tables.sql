@@ -0,0 +1,41 @@
+-- Revisions used to hold the code files as they were before each change, and
+-- adding a project recorded none. They now hold the files left by each
+-- change, the addition included. The revisions written before are tagged as
+-- 'before' snapshots, and the current files of every project without an
+-- 'after' snapshot are recorded. The old revisions then hold, in order, the
+-- files left by the addition and by each change but the last, and the new
+-- revision the files left by the last change, which is what the revisions
+-- hold now. It only records the projects not migrated yet, so it is safe to
+-- run on every start.
+ALTER TABLE projects_history ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE projects_code_files_history ADD COLUMN IF NOT EXISTS file_id INT;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'projects_history' AND column_name = 'snapshot'
+    ) THEN
+        ALTER TABLE projects_history ADD COLUMN snapshot VARCHAR(10) NOT NULL DEFAULT 'before';
+        ALTER TABLE projects_history ALTER COLUMN snapshot SET DEFAULT 'after';
+    END IF;
+END $$;
+
+WITH revisions AS (
+    INSERT INTO projects_history (project_id, snapshot)
+    SELECT p.id, 'after' FROM projects p
+    WHERE NOT EXISTS (
+        SELECT 1 FROM projects_history ph WHERE ph.project_id = p.id AND ph.snapshot = 'after'
+    )
+    ORDER BY p.id
+    RETURNING id, project_id
+)
+INSERT INTO projects_code_files_history (name, content, revision_id, file_id)
+SELECT cf.name, cf.content, r.id, cf.id
+FROM revisions r
+INNER JOIN code_files cf ON cf.project_id = r.project_id
+ORDER BY r.id, cf.created_at, cf.id;
+
+-- The high-water mark of the saved searches holds the id of the last match
+-- along with its update time, to tell apart the projects updated at once.
+ALTER TABLE saved_searches ADD COLUMN IF NOT EXISTS last_seen_id INT NOT NULL DEFAULT 0;
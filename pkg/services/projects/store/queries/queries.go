@@ -9,4 +9,6 @@ var (
 	QueryCreateTables string
 	//go:embed drop_tables.sql
 	QueryDropTables string
+	//go:embed migrate_tables.sql
+	QueryMigrateTables string
 )
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"lastimplementation.com/pkg/services/projects"
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+type savedSearchMatchRow struct {
+	ID int `boil:"id"`
+}
+
+type savedSearchRow struct {
+	ID         int       `boil:"id"`
+	Name       string    `boil:"name"`
+	Query      string    `boil:"query"`
+	LastSeenAt time.Time `boil:"last_seen_at"`
+	LastSeenID int       `boil:"last_seen_id"`
+	CreatedAt  time.Time `boil:"created_at"`
+}
+
+// AddSavedSearch saves a search. Only the projects matching it from now on are
+// new matches. The high-water mark is set in UTC, as the update times of the
+// projects are, rather than in the time zone of the database session.
+func (pr *projectsRepo) AddSavedSearch(ctx context.Context, search models.SavedSearch) (models.SavedSearch, error) {
+	log := pr.l.WithPrefix("addSavedSearch")
+
+	var row savedSearchRow
+	err := queries.Raw(`
+		INSERT INTO saved_searches (name, query, last_seen_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, name, query, last_seen_at, last_seen_id, created_at`,
+		search.Name, search.Query, time.Now().UTC(),
+	).Bind(ctx, pr.db, &row)
+	if err != nil {
+		log.Error("inserting saved search", err)
+		if strings.HasSuffix(err.Error(), ErrDuplicated("saved_searches_name_key")) {
+			return models.SavedSearch{}, projects.ErrAddSavedSearchDuplicatedName
+		}
+		return models.SavedSearch{}, err
+	}
+	return row.toModel(), nil
+}
+
+// GetSavedSearches fetches the saved searches by name.
+func (pr *projectsRepo) GetSavedSearches(ctx context.Context) (models.SavedSearches, error) {
+	log := pr.l.WithPrefix("getSavedSearches")
+
+	var rows []savedSearchRow
+	err := queries.Raw(`
+		SELECT id, name, query, last_seen_at, last_seen_id, created_at
+		FROM saved_searches
+		ORDER BY name`,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		log.Error("querying saved searches", err)
+		return nil, err
+	}
+
+	res := make(models.SavedSearches, len(rows))
+	for i, row := range rows {
+		res[i] = row.toModel()
+	}
+	return res, nil
+}
+
+// GetSavedSearch fetches a single saved search.
+func (pr *projectsRepo) GetSavedSearch(ctx context.Context, id int) (models.SavedSearch, error) {
+	log := pr.l.WithPrefix("getSavedSearch")
+
+	var row savedSearchRow
+	err := queries.Raw(`
+		SELECT id, name, query, last_seen_at, last_seen_id, created_at
+		FROM saved_searches
+		WHERE id = $1`,
+		id,
+	).Bind(ctx, pr.db, &row)
+	if err != nil {
+		log.Error("querying saved search", err)
+		if strings.HasSuffix(err.Error(), ErrNotResult()) {
+			return models.SavedSearch{}, projects.ErrSavedSearchNotFound
+		}
+		return models.SavedSearch{}, err
+	}
+	return row.toModel(), nil
+}
+
+// DeleteSavedSearch deletes a saved search.
+func (pr *projectsRepo) DeleteSavedSearch(ctx context.Context, id int) error {
+	log := pr.l.WithPrefix("deleteSavedSearch")
+
+	res, err := pr.db.ExecContext(ctx, "DELETE FROM saved_searches WHERE id = $1", id)
+	if err != nil {
+		log.Error("deleting saved search", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return projects.ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// MarkSavedSearchSeen moves the high-water mark of a saved search up to the
+// update time and id of the last match fetched. The mark never moves back.
+func (pr *projectsRepo) MarkSavedSearchSeen(ctx context.Context, id int, seenAt time.Time, seenId int) error {
+	log := pr.l.WithPrefix("markSavedSearchSeen")
+
+	if _, err := pr.db.ExecContext(ctx, `
+		UPDATE saved_searches
+		SET last_seen_at = $2, last_seen_id = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_seen_at, last_seen_id) < ($2, $3)`,
+		id, seenAt.UTC(), seenId,
+	); err != nil {
+		log.Error("updating saved search high-water mark", err)
+		return err
+	}
+	return nil
+}
+
+// MatchSavedSearches fetches the ids of the saved searches whose filters,
+// keyed by saved search id, match a project, checking all of them in a single
+// query.
+func (pr *projectsRepo) MatchSavedSearches(ctx context.Context, projectId int, filters map[int]models.Filter) ([]int, error) {
+	log := pr.l.WithPrefix("matchSavedSearches")
+
+	sq := &searchQuery{}
+	id := sq.arg(projectId)
+	searchIds := make([]int, 0, len(filters))
+	for searchId := range filters {
+		searchIds = append(searchIds, searchId)
+	}
+	sort.Ints(searchIds)
+	var selects []string
+	for _, searchId := range searchIds {
+		pred, err := sq.predicate(filters[searchId])
+		if err != nil {
+			log.Error("building search query", err)
+			return nil, err
+		}
+		selects = append(selects, fmt.Sprintf("SELECT %d AS id FROM projects p WHERE p.id = %s AND %s", searchId, id, pred))
+	}
+	if len(selects) == 0 {
+		return nil, nil
+	}
+
+	var rows []savedSearchMatchRow
+	if err := queries.Raw(strings.Join(selects, " UNION ALL "), sq.args...).Bind(ctx, pr.db, &rows); err != nil {
+		log.Error("matching saved searches", err)
+		return nil, err
+	}
+	res := make([]int, len(rows))
+	for i, row := range rows {
+		res[i] = row.ID
+	}
+	return res, nil
+}
+
+func (row savedSearchRow) toModel() models.SavedSearch {
+	return models.SavedSearch{
+		Id:         row.ID,
+		Name:       row.Name,
+		Query:      row.Query,
+		LastSeen:   row.LastSeenAt,
+		LastSeenId: row.LastSeenID,
+		LastSeenAt: row.LastSeenAt.Local().Unix(),
+		CreatedAt:  row.CreatedAt.Local().Unix(),
+	}
+}
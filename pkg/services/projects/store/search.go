@@ -0,0 +1,373 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"github.com/volatiletech/sqlboiler/v4/boil"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"lastimplementation.com/pkg/services/projects/models"
+	"lastimplementation.com/pkg/services/projects/search"
+)
+
+// searchQuery translates a search filter into the predicates and positional
+// arguments used to filter and rank the projects list.
+type searchQuery struct {
+	filter     models.Filter
+	where      []string
+	args       []interface{}
+	rank       string
+	similarity string
+}
+
+type projectRank struct {
+	ID         int     `boil:"id"`
+	Rank       float64 `boil:"rank"`
+	Similarity float64 `boil:"similarity"`
+	CursorKey  string  `boil:"cursor_key"`
+}
+
+type facetRow struct {
+	Facet string `boil:"facet"`
+	Value string `boil:"value"`
+	Count int    `boil:"count"`
+}
+
+type matchedFile struct {
+	ID      int    `boil:"id"`
+	Content string `boil:"content"`
+}
+
+func newSearchQuery(filter models.Filter) (*searchQuery, error) {
+	sq := &searchQuery{filter: filter, rank: "0", similarity: "0"}
+	if filter == nil {
+		return sq, nil
+	}
+	where, err := sq.predicate(filter)
+	if err != nil {
+		return nil, err
+	}
+	sq.where = append(sq.where, where)
+
+	var tsqs, terms []string
+	for _, f := range models.PositiveFilters(filter) {
+		if text, ok := f.(models.TextFilter); ok {
+			tsqs = append(tsqs, sq.tsQuery(text))
+			terms = append(terms, text.Value)
+		}
+	}
+	if len(terms) > 0 {
+		tsq := "(" + strings.Join(tsqs, " && ") + ")"
+		sq.rank = fmt.Sprintf(
+			"ts_rank(p.search_vector, %[1]s) + COALESCE((SELECT MAX(ts_rank(cf.search_vector, %[1]s)) FROM code_files cf WHERE cf.project_id = p.id AND cf.search_vector @@ %[1]s), 0)", tsq)
+		sq.similarity = fmt.Sprintf(
+			"GREATEST(similarity(p.name, %[1]s), COALESCE((SELECT MAX(similarity(cf.name, %[1]s)) FROM code_files cf WHERE cf.project_id = p.id), 0))",
+			sq.arg(strings.Join(terms, " ")))
+	}
+	return sq, nil
+}
+
+// arg registers a new positional argument and returns its placeholder.
+func (sq *searchQuery) arg(v interface{}) string {
+	sq.args = append(sq.args, v)
+	return fmt.Sprintf("$%d", len(sq.args))
+}
+
+// predicate translates a filter node into a SQL boolean expression over the projects table.
+func (sq *searchQuery) predicate(f models.Filter) (string, error) {
+	switch f := f.(type) {
+	case models.AndFilter:
+		return sq.join(f.Filters, " AND ", "TRUE")
+	case models.OrFilter:
+		return sq.join(f.Filters, " OR ", "FALSE")
+	case models.NotFilter:
+		pred, err := sq.predicate(f.Filter)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + pred, nil
+	case models.TextFilter:
+		tsq := sq.tsQuery(f)
+		return fmt.Sprintf(
+			"(p.search_vector @@ %[1]s OR %[2]s OR EXISTS (SELECT 1 FROM code_files cf WHERE cf.project_id = p.id AND (cf.search_vector @@ %[1]s OR %[3]s)))",
+			tsq, sq.namesMatch("p.name", f), sq.namesMatch("cf.name", f)), nil
+	case models.TagFilter:
+		return fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM projects_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.project_id = p.id AND UPPER(t.name) = %s)",
+			sq.arg(string(f.Name))), nil
+	case models.FileFilter:
+		return fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM code_files cf WHERE cf.project_id = p.id AND cf.name ILIKE %s)",
+			sq.arg(filePattern(f.Name))), nil
+	case models.LangFilter:
+		return fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM code_files cf WHERE cf.project_id = p.id AND cf.language = %s)",
+			sq.arg(f.Language)), nil
+	case models.UpdatedFilter:
+		// The dates are stored without a time zone, in UTC, so the bound is
+		// converted to UTC rather than having its offset dropped.
+		if f.Op == models.CompareEq {
+			return fmt.Sprintf("p.updated_at::date = %s::date", sq.arg(f.Time.UTC())), nil
+		}
+		return fmt.Sprintf("p.updated_at %s %s", f.Op, sq.arg(f.Time.UTC())), nil
+	case models.CreatedFilter:
+		if f.Op == models.CompareEq {
+			return fmt.Sprintf("p.created_at::date = %s::date", sq.arg(f.Time.UTC())), nil
+		}
+		return fmt.Sprintf("p.created_at %s %s", f.Op, sq.arg(f.Time.UTC())), nil
+	default:
+		return "", fmt.Errorf("unsupported filter %T", f)
+	}
+}
+
+// join translates a list of filters and joins their predicates with a boolean operator.
+func (sq *searchQuery) join(filters []models.Filter, op, empty string) (string, error) {
+	if len(filters) == 0 {
+		return empty, nil
+	}
+	preds := make([]string, len(filters))
+	for i, child := range filters {
+		pred, err := sq.predicate(child)
+		if err != nil {
+			return "", err
+		}
+		preds[i] = pred
+	}
+	return "(" + strings.Join(preds, op) + ")", nil
+}
+
+// tsQuery returns the text search query expression for a text filter, matching
+// either its text or any of its alternatives.
+func (sq *searchQuery) tsQuery(f models.TextFilter) string {
+	fn := "plainto_tsquery"
+	if f.Phrase {
+		fn = "phraseto_tsquery"
+	}
+	values := f.Values()
+	tsqs := make([]string, len(values))
+	for i, v := range values {
+		tsqs[i] = fmt.Sprintf("%s('simple', %s)", fn, sq.arg(v))
+	}
+	if len(tsqs) == 1 {
+		return tsqs[0]
+	}
+	return "(" + strings.Join(tsqs, " || ") + ")"
+}
+
+// namesMatch returns the predicate matching a name column against the text of
+// a text filter or any of its alternatives.
+func (sq *searchQuery) namesMatch(column string, f models.TextFilter) string {
+	values := f.Values()
+	preds := make([]string, len(values))
+	for i, v := range values {
+		preds[i] = sq.nameMatch(column, v)
+	}
+	if len(preds) == 1 {
+		return preds[0]
+	}
+	return "(" + strings.Join(preds, " OR ") + ")"
+}
+
+// nameMatch returns the predicate matching a name column against some text.
+// It uses trigram similarity, unless the text is too short to build trigrams
+// from, in which case it falls back to a case-insensitive substring match.
+func (sq *searchQuery) nameMatch(column, text string) string {
+	if utf8.RuneCountInString(text) >= search.MinTrigramLength {
+		return fmt.Sprintf("%s %% %s::text", column, sq.arg(text))
+	}
+	return fmt.Sprintf("%s ILIKE '%%' || %s::text || '%%'", column, sq.arg(escapeLike(text)))
+}
+
+func (sq *searchQuery) whereClause() string {
+	if len(sq.where) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(sq.where, " AND ")
+}
+
+// count returns the number of projects matching the search.
+func (sq *searchQuery) count(ctx context.Context, exec boil.ContextExecutor) (int, error) {
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM projects p %s", sq.whereClause())
+	if err := exec.QueryRowContext(ctx, query, sq.args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting projects: %w", err)
+	}
+	return count, nil
+}
+
+// facets counts the projects matching the search per tag, code file language
+// and last updated bucket, being this week, month, year or older. The update
+// times are stored in UTC, so the buckets start on UTC boundaries as in the
+// in-memory index rather than in the time zone of the database session.
+func (sq *searchQuery) facets(ctx context.Context, exec boil.ContextExecutor) (*models.Facets, error) {
+	query := fmt.Sprintf(`
+		WITH filtered AS (SELECT p.id, p.updated_at FROM projects p %s)
+		SELECT 'tag' AS facet, t.name AS value, COUNT(DISTINCT f.id) AS count
+		FROM filtered f
+		INNER JOIN projects_tags pt ON pt.project_id = f.id
+		INNER JOIN tags t ON t.id = pt.tag_id
+		GROUP BY t.name
+		UNION ALL
+		SELECT 'language', cf.language, COUNT(DISTINCT f.id)
+		FROM filtered f
+		INNER JOIN code_files cf ON cf.project_id = f.id
+		WHERE cf.language <> ''
+		GROUP BY cf.language
+		UNION ALL
+		SELECT 'updated', b.bucket, COUNT(*)
+		FROM (
+			SELECT CASE
+				WHEN f.updated_at >= date_trunc('week', NOW() AT TIME ZONE 'UTC') THEN 'week'
+				WHEN f.updated_at >= date_trunc('month', NOW() AT TIME ZONE 'UTC') THEN 'month'
+				WHEN f.updated_at >= date_trunc('year', NOW() AT TIME ZONE 'UTC') THEN 'year'
+				ELSE 'older'
+			END AS bucket
+			FROM filtered f
+		) b
+		GROUP BY b.bucket
+		ORDER BY facet, count DESC, value`, sq.whereClause())
+
+	var rows []facetRow
+	if err := queries.Raw(query, sq.args...).Bind(ctx, exec, &rows); err != nil {
+		return nil, fmt.Errorf("counting facets: %w", err)
+	}
+
+	res := &models.Facets{Tags: []models.FacetCount{}, Languages: []models.FacetCount{}}
+	updated := make(map[string]int)
+	for _, row := range rows {
+		count := models.FacetCount{Value: row.Value, Count: row.Count}
+		switch row.Facet {
+		case "tag":
+			res.Tags = append(res.Tags, count)
+		case "language":
+			res.Languages = append(res.Languages, count)
+		case "updated":
+			updated[row.Value] = row.Count
+		}
+	}
+	for _, bucket := range models.UpdatedBuckets {
+		res.Updated = append(res.Updated, models.FacetCount{Value: bucket, Count: updated[bucket]})
+	}
+	return res, nil
+}
+
+// sortKeys maps the sort fields to their SQL expressions over the ranked projects.
+var sortKeys = map[models.SortField]string{
+	models.SortByName:      "s.name",
+	models.SortByUpdatedAt: "s.updated_at",
+	models.SortByCreatedAt: "s.created_at",
+	models.SortByRelevance: "s.rank + s.similarity",
+	models.SortByFileCount: "(SELECT COUNT(*) FROM code_files cf WHERE cf.project_id = s.id)",
+}
+
+// sortKeyTypes maps the sort fields to the SQL types of their keys, used to
+// read back the keys stored in the cursors.
+var sortKeyTypes = map[models.SortField]string{
+	models.SortByName:      "text",
+	models.SortByUpdatedAt: "timestamp",
+	models.SortByCreatedAt: "timestamp",
+	models.SortByRelevance: "float8",
+	models.SortByFileCount: "bigint",
+}
+
+// sortKeyTexts maps the sort fields to the SQL expressions of their cursor
+// keys, in the formats read by models.Cursor.
+var sortKeyTexts = map[models.SortField]string{
+	models.SortByName:      "k.sort_key",
+	models.SortByUpdatedAt: `to_char(k.sort_key, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')`,
+	models.SortByCreatedAt: `to_char(k.sort_key, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')`,
+	models.SortByRelevance: "k.sort_key::text",
+	models.SortByFileCount: "k.sort_key::text",
+}
+
+// page returns the projects in the requested page, plus the first project of
+// the next one when it exists. The projects are sorted by the requested field,
+// falling back to their id to keep the order stable. The page either starts
+// right after the cursor, when there is one, or at the offset of the page number.
+func (sq *searchQuery) page(ctx context.Context, exec boil.ContextExecutor, qp models.SearchQP) ([]projectRank, error) {
+	sortKey, ok := sortKeys[qp.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", qp.Sort)
+	}
+	order, cmp := "ASC", ">"
+	if qp.Order == models.SortDesc {
+		order, cmp = "DESC", "<"
+	}
+
+	kq := &searchQuery{args: append([]interface{}{}, sq.args...)}
+	after, offset := "", 0
+	if qp.Cursor != nil {
+		key, err := qp.Cursor.SortValue()
+		if err != nil {
+			return nil, err
+		}
+		after = fmt.Sprintf("WHERE (k.sort_key, k.id) %s (%s::%s, %s)",
+			cmp, kq.arg(key), sortKeyTypes[qp.Sort], kq.arg(qp.Cursor.Id))
+	} else {
+		offset = (qp.Page - 1) * qp.Limit
+	}
+	query := fmt.Sprintf(
+		`SELECT k.id, k.rank, k.similarity, %s AS cursor_key FROM (
+			SELECT s.id, s.rank, s.similarity, %s AS sort_key FROM (
+				SELECT p.id, p.name, p.created_at, p.updated_at, %s AS rank, %s AS similarity FROM projects p %s
+			) s
+		) k %s ORDER BY k.sort_key %s, k.id %s LIMIT %s OFFSET %s`,
+		sortKeyTexts[qp.Sort], sortKey, sq.rank, sq.similarity, sq.whereClause(), after, order, order, kq.arg(qp.Limit+1), kq.arg(offset))
+
+	var ranks []projectRank
+	if err := queries.Raw(query, kq.args...).Bind(ctx, exec, &ranks); err != nil {
+		return nil, fmt.Errorf("ranking projects: %w", err)
+	}
+	return ranks, nil
+}
+
+// matchedFiles returns the code files, from the given projects, that match the
+// text or the file name filters of the search, along with the snippets where
+// the text matched their content.
+func (sq *searchQuery) matchedFiles(ctx context.Context, exec boil.ContextExecutor, projectIds []int) (map[int][]models.Snippet, error) {
+	matched := make(map[int][]models.Snippet)
+	if len(projectIds) == 0 {
+		return matched, nil
+	}
+	fq := &searchQuery{}
+	var preds, terms []string
+	for _, f := range models.PositiveFilters(sq.filter) {
+		switch f := f.(type) {
+		case models.TextFilter:
+			preds = append(preds, fmt.Sprintf("cf.search_vector @@ %s OR %s", fq.tsQuery(f), fq.namesMatch("cf.name", f)))
+			for _, v := range f.Values() {
+				terms = append(terms, search.Terms(v, f.Phrase)...)
+			}
+		case models.FileFilter:
+			preds = append(preds, fmt.Sprintf("cf.name ILIKE %s", fq.arg(filePattern(f.Name))))
+		}
+	}
+	if len(preds) == 0 {
+		return matched, nil
+	}
+	query := fmt.Sprintf(
+		"SELECT cf.id, cf.content FROM code_files cf WHERE cf.project_id = ANY(%s) AND (%s)",
+		fq.arg(pq.Array(projectIds)), strings.Join(preds, " OR "))
+
+	var files []matchedFile
+	if err := queries.Raw(query, fq.args...).Bind(ctx, exec, &files); err != nil {
+		return nil, fmt.Errorf("matching code files: %w", err)
+	}
+	for _, f := range files {
+		matched[f.ID] = search.Snippets(f.ID, f.Content, terms)
+	}
+	return matched, nil
+}
+
+// filePattern converts a file name, with optional '*' wildcards, into a LIKE pattern.
+func filePattern(name string) string {
+	return strings.ReplaceAll(escapeLike(name), "*", "%")
+}
+
+// escapeLike escapes the LIKE wildcards from a string.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
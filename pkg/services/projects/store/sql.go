@@ -41,6 +41,17 @@ func (pr *projectsRepo) Reset(ctx context.Context) error {
 	return nil
 }
 
+// Migrate brings the projects tables written by previous versions up to date.
+func (pr *projectsRepo) Migrate(ctx context.Context) error {
+	log := pr.l.WithPrefix("migrate")
+
+	log.Trace("Migrating the projects repo")
+	if _, err := pr.db.ExecContext(ctx, queries.QueryMigrateTables); err != nil {
+		return fmt.Errorf("executing query: %w", err)
+	}
+	return nil
+}
+
 // Add inserts a new project.
 func (pr *projectsRepo) Add(ctx context.Context, project models.Project) (int, error) {
 	log := pr.l.WithPrefix("add")
@@ -73,6 +84,18 @@ func (pr *projectsRepo) Add(ctx context.Context, project models.Project) (int, e
 		return -1, err
 	}
 
+	if err := pr.indexFiles(ctx, tx, p.ID); err != nil {
+		log.Error("indexing the project files", err)
+		tx.Rollback()
+		return -1, err
+	}
+
+	if err := pr.snapshotFiles(ctx, tx, p.ID); err != nil {
+		log.Error("recording the project first revision", err)
+		tx.Rollback()
+		return -1, err
+	}
+
 	tx.Commit()
 	return p.ID, nil
 }
@@ -177,6 +200,13 @@ func (pr *projectsRepo) Get(ctx context.Context, id int) (models.Project, error)
 		}
 		return models.Project{}, err
 	}
+	languages, err := pr.fileLanguages(ctx, tx, []int{p.ID})
+	if err != nil {
+		log.Error("getting project files language", err)
+		tx.Rollback()
+		return models.Project{}, err
+	}
+
 	tx.Commit()
 
 	res := models.Project{
@@ -193,9 +223,10 @@ func (pr *projectsRepo) Get(ctx context.Context, id int) (models.Project, error)
 
 	for _, cf := range p.R.CodeFiles {
 		res.Files = append(res.Files, models.CodeFile{
-			Id:      cf.ID,
-			Name:    cf.Name,
-			Content: cf.Content,
+			Id:       cf.ID,
+			Name:     cf.Name,
+			Content:  cf.Content,
+			Language: languages[cf.ID],
 		})
 	}
 
@@ -203,7 +234,7 @@ func (pr *projectsRepo) Get(ctx context.Context, id int) (models.Project, error)
 }
 
 // GetAll fetches the projects list.
-func (pr *projectsRepo) GetAll(ctx context.Context, query string, page, limit int) (models.ProjectsList, error) {
+func (pr *projectsRepo) GetAll(ctx context.Context, qp models.SearchQP) (models.ProjectsList, error) {
 	log := pr.l.WithPrefix("getAll")
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -215,68 +246,149 @@ func (pr *projectsRepo) GetAll(ctx context.Context, query string, page, limit in
 		return models.ProjectsList{}, err
 	}
 
-	projects, err := dao.Projects(
-		qm.Select(dao.ProjectColumns.ID, dao.ProjectColumns.Name, dao.ProjectColumns.Description, dao.ProjectColumns.UpdatedAt),
-		qm.Where("LOWER(name) ~ ?", strings.ToLower(query)),
-		qm.Offset((page-1)*limit),
-		qm.Limit(limit),
-		qm.OrderBy(dao.ProjectColumns.Name),
-		qm.Load(dao.ProjectRels.CodeFiles,
-			qm.Select(dao.CodeFileColumns.ProjectID, dao.CodeFileColumns.ID, dao.CodeFileColumns.Name, dao.CodeFileColumns.CreatedAt),
-			qm.OrderBy(dao.CodeFileColumns.CreatedAt)),
-		qm.Load(dao.ProjectRels.ProjectsTags,
-			qm.Select(dao.ProjectsTagColumns.ProjectID, dao.ProjectsTagColumns.TagID)),
-		qm.Load(qm.Rels(dao.ProjectRels.ProjectsTags, dao.ProjectsTagRels.Tag),
-			qm.Select(dao.TagColumns.ID, dao.TagColumns.Name)),
-	).All(ctx, tx)
+	sq, err := newSearchQuery(qp.Filter)
 	if err != nil {
-		log.Error("getting project items", err)
+		log.Error("building search query", err)
 		tx.Rollback()
 		return models.ProjectsList{}, err
 	}
 
-	count, err := dao.Projects().Count(ctx, pr.db)
+	ranks, err := sq.page(ctx, tx, qp)
+	if err != nil {
+		log.Error("searching project items", err)
+		tx.Rollback()
+		return models.ProjectsList{}, err
+	}
+
+	count, err := sq.count(ctx, tx)
 	if err != nil {
 		log.Error("counting total project items", err)
 		tx.Rollback()
 		return models.ProjectsList{}, err
 	}
 
+	facets, err := sq.facets(ctx, tx)
+	if err != nil {
+		log.Error("counting project items facets", err)
+		tx.Rollback()
+		return models.ProjectsList{}, err
+	}
+
+	var nextCursor string
+	if len(ranks) > qp.Limit {
+		ranks = ranks[:qp.Limit]
+		last := ranks[len(ranks)-1]
+		nextCursor = qp.NextCursor(last.CursorKey, last.ID).Encode()
+	}
+
+	projectIds := make([]int, len(ranks))
+	for i, r := range ranks {
+		projectIds[i] = r.ID
+	}
+
+	projects, err := pr.loadProjectItems(ctx, tx, projectIds)
+	if err != nil {
+		log.Error("getting project items", err)
+		tx.Rollback()
+		return models.ProjectsList{}, err
+	}
+
+	matched, err := sq.matchedFiles(ctx, tx, projectIds)
+	if err != nil {
+		log.Error("getting matched code files", err)
+		tx.Rollback()
+		return models.ProjectsList{}, err
+	}
+
+	languages, err := pr.fileLanguages(ctx, tx, projectIds)
+	if err != nil {
+		log.Error("getting code files language", err)
+		tx.Rollback()
+		return models.ProjectsList{}, err
+	}
+
 	tx.Commit()
 
-	projectList := models.ProjectsList{Data: make([]models.ProjectItem, len(projects))}
+	projectList := models.ProjectsList{Data: make([]models.ProjectItem, 0, len(ranks))}
 
-	for i, p := range projects {
-		projectList.Data[i] = models.ProjectItem{
+	for _, r := range ranks {
+		p, ok := projects[r.ID]
+		if !ok {
+			continue
+		}
+		item := models.ProjectItem{
 			Id:          p.ID,
 			Name:        p.Name,
 			Description: p.Description,
+			CreatedAt:   p.CreatedAt.Local().Unix(),
 			UpdatedAt:   p.UpdatedAt.Local().Unix(),
+			Rank:        r.Rank,
+			Similarity:  r.Similarity,
 			Files:       make([]models.ProjectItemFile, len(p.R.CodeFiles)),
+			Cursor:      qp.NextCursor(r.CursorKey, r.ID),
 			Tags:        make([]models.Tag, len(p.R.ProjectsTags)),
 		}
 		for j, cf := range p.R.CodeFiles {
-			projectList.Data[i].Files[j] = models.ProjectItemFile{
-				Id:   cf.ID,
-				Name: cf.Name,
+			snippets, ok := matched[cf.ID]
+			item.Files[j] = models.ProjectItemFile{
+				Id:       cf.ID,
+				Name:     cf.Name,
+				Language: languages[cf.ID],
+				Matched:  ok,
+				Snippets: snippets,
 			}
 		}
 		for j, tag := range p.R.ProjectsTags {
-			projectList.Data[i].Tags[j] = models.Tag{
+			item.Tags[j] = models.Tag{
 				Id:   tag.TagID,
 				Name: models.TagType(tag.R.Tag.Name),
 			}
 		}
+		projectList.Data = append(projectList.Data, item)
 	}
 
-	projectList.TotalItems = int(count)
-	projectList.Page = page
+	projectList.TotalItems = count
+	projectList.Facets = facets
+	projectList.NextCursor = nextCursor
+	if qp.Cursor == nil {
+		projectList.Page = qp.Page
+	}
 	projectList.Count = len(projectList.Data)
-	projectList.TotalPages = int(math.Ceil(float64(projectList.TotalItems) / float64(limit)))
+	projectList.TotalPages = int(math.Ceil(float64(projectList.TotalItems) / float64(qp.Limit)))
 
 	return projectList, nil
 }
 
+// loadProjectItems fetches the projects with the given ids, along with their files and tags.
+func (pr *projectsRepo) loadProjectItems(ctx context.Context, tx *sql.Tx, ids []int) (map[int]*dao.Project, error) {
+	res := make(map[int]*dao.Project, len(ids))
+	if len(ids) == 0 {
+		return res, nil
+	}
+	idsArg := make([]interface{}, len(ids))
+	for i, id := range ids {
+		idsArg[i] = id
+	}
+	projects, err := dao.Projects(
+		qm.Select(dao.ProjectColumns.ID, dao.ProjectColumns.Name, dao.ProjectColumns.Description, dao.ProjectColumns.CreatedAt, dao.ProjectColumns.UpdatedAt),
+		qm.WhereIn("id IN ?", idsArg...),
+		qm.Load(dao.ProjectRels.CodeFiles,
+			qm.Select(dao.CodeFileColumns.ProjectID, dao.CodeFileColumns.ID, dao.CodeFileColumns.Name, dao.CodeFileColumns.CreatedAt),
+			qm.OrderBy(dao.CodeFileColumns.CreatedAt)),
+		qm.Load(dao.ProjectRels.ProjectsTags,
+			qm.Select(dao.ProjectsTagColumns.ProjectID, dao.ProjectsTagColumns.TagID)),
+		qm.Load(qm.Rels(dao.ProjectRels.ProjectsTags, dao.ProjectsTagRels.Tag),
+			qm.Select(dao.TagColumns.ID, dao.TagColumns.Name)),
+	).All(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		res[p.ID] = p
+	}
+	return res, nil
+}
+
 // Update updates the data from an existing project.
 func (pr *projectsRepo) Update(ctx context.Context, id int, project models.ProjectDetails) error {
 	log := pr.l.WithPrefix("update")
@@ -403,17 +515,52 @@ func (pr *projectsRepo) GetFiles(ctx context.Context, projectId int) (models.Cod
 		log.Error("fetching code file for an existing project", err)
 		return nil, err
 	}
+	languages, err := pr.fileLanguages(ctx, pr.db, []int{projectId})
+	if err != nil {
+		log.Error("fetching code files language", err)
+		return nil, err
+	}
 	files := make([]models.CodeFile, len(dbFiles))
 	for i, dbFile := range dbFiles {
 		files[i] = models.CodeFile{
-			Id:      dbFile.ID,
-			Name:    dbFile.Name,
-			Content: dbFile.Content,
+			Id:       dbFile.ID,
+			Name:     dbFile.Name,
+			Content:  dbFile.Content,
+			Language: languages[dbFile.ID],
 		}
 	}
 	return files, nil
 }
 
+// ScanFiles walks through the code files of every project, ordered by project,
+// until fn returns an error.
+func (pr *projectsRepo) ScanFiles(ctx context.Context, fn func(models.ProjectFile) error) error {
+	log := pr.l.WithPrefix("scanFiles")
+
+	rows, err := pr.db.QueryContext(ctx, `
+		SELECT p.id, p.name, cf.id, cf.name, cf.content
+		FROM code_files cf
+		INNER JOIN projects p ON p.id = cf.project_id
+		ORDER BY p.id, cf.id`)
+	if err != nil {
+		log.Error("querying code files", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f models.ProjectFile
+		if err := rows.Scan(&f.ProjectId, &f.ProjectName, &f.Id, &f.Name, &f.Content); err != nil {
+			log.Error("reading code file", err)
+			return err
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // UpdateFiles updates the files data for a given project.
 func (pr *projectsRepo) UpdateFiles(ctx context.Context, projectId int, files []models.CodeFile) error {
 	log := pr.l.WithPrefix("updateFiles")
@@ -424,53 +571,50 @@ func (pr *projectsRepo) UpdateFiles(ctx context.Context, projectId int, files []
 		return err
 	}
 
-	_, err = dao.Projects(
+	if err := pr.updateFiles(ctx, tx, projectId, files); err != nil {
+		log.Error("updating project files", err)
+		tx.Rollback()
+		return err
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// updateFiles merges the code files of a project, reindexes them and records
+// the result as a new revision.
+func (pr *projectsRepo) updateFiles(ctx context.Context, tx *sql.Tx, projectId int, files []models.CodeFile) error {
+	p, err := dao.Projects(
 		qm.Select(dao.ProjectColumns.ID),
 		qm.Where("id = ?", projectId),
 	).One(ctx, tx)
 	if err != nil {
-		log.Error("finding project", err)
-		tx.Rollback()
 		if strings.HasSuffix(err.Error(), ErrNotResult()) {
 			return projects.ErrProjectNotFound
 		}
-		return err
-	}
-
-	dbHistProj := dao.ProjectsHistory{ProjectID: projectId}
-	if err := dbHistProj.Insert(ctx, tx, boil.Infer()); err != nil {
-		log.Error("inserting project revision history", err)
-		tx.Rollback()
-		return err
+		return fmt.Errorf("finding project: %w", err)
 	}
 
 	dbFiles, err := dao.CodeFiles(qm.Where("project_id = ?", projectId)).All(ctx, tx)
 	if err != nil {
-		log.Error("getting project files")
-		tx.Rollback()
-		return err
+		return fmt.Errorf("getting project files: %w", err)
 	}
 
-	for _, dbFile := range dbFiles {
-		dbHistFile := dao.ProjectsCodeFilesHistory{
-			Name:       dbFile.Name,
-			Content:    dbFile.Content,
-			RevisionID: dbHistProj.ID,
-		}
-		if err := dbHistFile.Insert(ctx, tx, boil.Infer()); err != nil {
-			log.Error("inserting code file to history", err)
-			tx.Rollback()
-			return err
-		}
+	if err := pr.mergeFiles(ctx, tx, projectId, files, dbFiles); err != nil {
+		return fmt.Errorf("merging project files: %w", err)
 	}
 
-	if err := pr.mergeFiles(ctx, tx, projectId, files, dbFiles); err != nil {
-		log.Error("merging project files", err)
-		tx.Rollback()
-		return err
+	if _, err := p.Update(ctx, tx, boil.Whitelist(dao.ProjectColumns.UpdatedAt)); err != nil {
+		return fmt.Errorf("updating project: %w", err)
 	}
 
-	tx.Commit()
+	if err := pr.indexFiles(ctx, tx, projectId); err != nil {
+		return fmt.Errorf("indexing the project files: %w", err)
+	}
+
+	if err := pr.snapshotFiles(ctx, tx, projectId); err != nil {
+		return fmt.Errorf("recording project revision: %w", err)
+	}
 	return nil
 }
 
@@ -505,9 +649,12 @@ func (pr *projectsRepo) mergeFiles(ctx context.Context, tx *sql.Tx, projectId in
 
 	for _, dbFile := range dbFilesToUpdate {
 		file := filesToUpdateMap[dbFile.ID]
+		if dbFile.Name == file.Name && dbFile.Content == file.Content {
+			continue
+		}
 		dbFile.Name = file.Name
 		dbFile.Content = file.Content
-		if _, err := dbFile.Update(ctx, tx, boil.Whitelist(dao.CodeFileColumns.Name, dao.CodeFileColumns.Content)); err != nil {
+		if _, err := dbFile.Update(ctx, tx, boil.Whitelist(dao.CodeFileColumns.Name, dao.CodeFileColumns.Content, dao.CodeFileColumns.UpdatedAt)); err != nil {
 			return fmt.Errorf("updating existing project file %d: %w", dbFile.ID, err)
 		}
 	}
@@ -519,13 +666,48 @@ func (pr *projectsRepo) mergeFiles(ctx context.Context, tx *sql.Tx, projectId in
 	return nil
 }
 
+// setFiles replaces the code files of a project with files that have no ids.
+// The existing files are matched by name and updated in place, so they keep
+// their ids and their history can be followed across revisions. The files
+// left unmatched are deleted, and the new ones are added.
 func (pr *projectsRepo) setFiles(ctx context.Context, tx *sql.Tx, projectId int, files []models.CodeFile, dbFiles dao.CodeFileSlice) error {
-	if _, err := dbFiles.DeleteAll(ctx, tx); err != nil {
-		return fmt.Errorf("deleting all existing project files: %w", err)
+	byName := make(map[string]dao.CodeFileSlice)
+	for _, dbFile := range dbFiles {
+		byName[dbFile.Name] = append(byName[dbFile.Name], dbFile)
+	}
+
+	kept := make(map[int]bool)
+	var filesToAdd []models.CodeFile
+	for _, file := range files {
+		candidates := byName[file.Name]
+		if len(candidates) == 0 {
+			filesToAdd = append(filesToAdd, file)
+			continue
+		}
+		dbFile := candidates[0]
+		byName[file.Name] = candidates[1:]
+		kept[dbFile.ID] = true
+		if dbFile.Content == file.Content {
+			continue
+		}
+		dbFile.Content = file.Content
+		if _, err := dbFile.Update(ctx, tx, boil.Whitelist(dao.CodeFileColumns.Content, dao.CodeFileColumns.UpdatedAt)); err != nil {
+			return fmt.Errorf("updating existing project file %d: %w", dbFile.ID, err)
+		}
 	}
 
-	if err := pr.addFiles(ctx, tx, projectId, files); err != nil {
-		return fmt.Errorf("adding all the new project files: %w", err)
+	var dbFilesToDelete dao.CodeFileSlice
+	for _, dbFile := range dbFiles {
+		if !kept[dbFile.ID] {
+			dbFilesToDelete = append(dbFilesToDelete, dbFile)
+		}
+	}
+	if _, err := dbFilesToDelete.DeleteAll(ctx, tx); err != nil {
+		return fmt.Errorf("deleting the missing project files: %w", err)
+	}
+
+	if err := pr.addFiles(ctx, tx, projectId, filesToAdd); err != nil {
+		return fmt.Errorf("adding the new project files: %w", err)
 	}
 
 	return nil
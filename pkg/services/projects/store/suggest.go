@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+type suggestionRow struct {
+	Kind      string `boil:"kind"`
+	ID        int    `boil:"id"`
+	Text      string `boil:"text"`
+	ProjectID int    `boil:"project_id"`
+}
+
+// Suggest completes a prefix with the names of the projects, tags and code
+// files, ignoring case. Exact matches and shorter names come first. Each name
+// is looked up through its own prefix index, so suggestions stay cheap enough
+// to be requested on every keystroke.
+func (pr *projectsRepo) Suggest(ctx context.Context, qp models.SuggestQP) (models.Suggestions, error) {
+	log := pr.l.WithPrefix("suggest")
+
+	prefix := strings.ToLower(qp.Prefix)
+	var rows []suggestionRow
+	err := queries.Raw(`
+		SELECT kind, id, text, project_id FROM (
+			(SELECT 'project' AS kind, 0 AS priority, id, name AS text, 0 AS project_id
+			FROM projects
+			WHERE LOWER(name) LIKE $1::text || '%'
+			ORDER BY LOWER(name) = $2 DESC, LENGTH(name), name
+			LIMIT $3)
+			UNION ALL
+			(SELECT 'tag', 1, id, name, 0
+			FROM tags
+			WHERE LOWER(name) LIKE $1::text || '%'
+			ORDER BY LOWER(name) = $2 DESC, LENGTH(name), name
+			LIMIT $3)
+			UNION ALL
+			(SELECT 'file', 2, id, name, project_id
+			FROM code_files
+			WHERE LOWER(name) LIKE $1::text || '%'
+			ORDER BY LOWER(name) = $2 DESC, LENGTH(name), name, id
+			LIMIT $3)
+		) s
+		ORDER BY LOWER(text) = $2 DESC, LENGTH(text), priority, text, id
+		LIMIT $3`,
+		escapeLike(prefix), prefix, qp.Limit,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		log.Error("querying suggestions", err)
+		return nil, err
+	}
+
+	res := make(models.Suggestions, len(rows))
+	for i, row := range rows {
+		res[i] = models.Suggestion{
+			Kind:      models.SuggestionKind(row.Kind),
+			Id:        row.ID,
+			Text:      row.Text,
+			ProjectId: row.ProjectID,
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"lastimplementation.com/pkg/services/projects/models"
+	"lastimplementation.com/pkg/services/projects/symbols"
+)
+
+type implementationRow struct {
+	symbolRow     `boil:",bind"`
+	FileUpdatedAt time.Time `boil:"file_updated_at"`
+}
+
+type symbolRow struct {
+	Kind        string `boil:"kind"`
+	Name        string `boil:"name"`
+	Receiver    string `boil:"receiver"`
+	Signature   string `boil:"signature"`
+	Digest      string `boil:"digest"`
+	Line        int    `boil:"line"`
+	ProjectID   int    `boil:"project_id"`
+	ProjectName string `boil:"project_name"`
+	FileID      int    `boil:"file_id"`
+	FileName    string `boil:"file_name"`
+}
+
+// indexSymbols rebuilds the symbols index of a project from its Go code files.
+// Files that fail to parse are left out of the index.
+func (pr *projectsRepo) indexSymbols(ctx context.Context, tx *sql.Tx, projectId int, files []indexedFile) error {
+	log := pr.l.WithPrefix("indexSymbols")
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM code_symbols WHERE project_id = $1", projectId); err != nil {
+		return fmt.Errorf("deleting project symbols: %w", err)
+	}
+
+	for _, f := range files {
+		if f.Language != "go" {
+			continue
+		}
+		syms, err := symbols.Parse(f.Name, f.Content)
+		if err != nil {
+			log.Debug(fmt.Sprintf("skipping file %d", f.ID), err)
+			continue
+		}
+		for _, sym := range syms {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO code_symbols (project_id, file_id, kind, name, receiver, signature, digest, line)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+				projectId, f.ID, sym.Kind, sym.Name, sym.Receiver, sym.Signature, sym.Digest, sym.Line,
+			); err != nil {
+				return fmt.Errorf("inserting symbol %q: %w", sym.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// FindSymbols fetches the symbols whose name starts with the requested one,
+// ignoring case. Exact matches come first. Methods are narrowed down to the
+// requested receiver, if any, whether it is a pointer or not.
+func (pr *projectsRepo) FindSymbols(ctx context.Context, qp models.SymbolQP) (models.Symbols, error) {
+	log := pr.l.WithPrefix("findSymbols")
+
+	var rows []symbolRow
+	err := queries.Raw(`
+		SELECT s.kind, s.name, s.receiver, s.signature, s.digest, s.line, s.project_id, p.name AS project_name, s.file_id, cf.name AS file_name
+		FROM code_symbols s
+		INNER JOIN projects p ON p.id = s.project_id
+		INNER JOIN code_files cf ON cf.id = s.file_id
+		WHERE LOWER(s.name) LIKE LOWER($2::text) || '%' AND ($3 = '' OR s.kind = $3)
+			AND ($5 = '' OR LOWER(LTRIM(s.receiver, '*')) = LOWER($5))
+		ORDER BY LOWER(s.name) = LOWER($1::text) DESC, s.name = $1 DESC, LENGTH(s.name), s.name, s.project_id, s.file_id, s.line
+		LIMIT $4`,
+		qp.Name, escapeLike(qp.Name), string(qp.Kind), qp.Limit, qp.Receiver,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		log.Error("querying symbols", err)
+		return nil, err
+	}
+
+	res := make(models.Symbols, len(rows))
+	for i, row := range rows {
+		res[i] = row.toModel()
+	}
+	return res, nil
+}
+
+// FindImplementations fetches the functions, methods and types with the
+// requested name, ignoring case, from the most recently updated code files.
+// Methods are narrowed down to the requested receiver, if any.
+func (pr *projectsRepo) FindImplementations(ctx context.Context, qp models.SymbolQP) (models.Implementations, error) {
+	log := pr.l.WithPrefix("findImplementations")
+
+	kinds := []string{string(models.SymbolKindFunc), string(models.SymbolKindMethod), string(models.SymbolKindType)}
+	if qp.Kind != "" {
+		kinds = []string{string(qp.Kind)}
+	}
+
+	var rows []implementationRow
+	err := queries.Raw(`
+		SELECT s.kind, s.name, s.receiver, s.signature, s.digest, s.line, s.project_id, p.name AS project_name,
+			s.file_id, cf.name AS file_name, cf.updated_at AS file_updated_at
+		FROM code_symbols s
+		INNER JOIN projects p ON p.id = s.project_id
+		INNER JOIN code_files cf ON cf.id = s.file_id
+		WHERE LOWER(s.name) = LOWER($1::text) AND s.kind = ANY($2)
+			AND ($4 = '' OR LOWER(LTRIM(s.receiver, '*')) = LOWER($4))
+		ORDER BY cf.updated_at DESC, s.project_id, s.file_id, s.line
+		LIMIT $3`,
+		qp.Name, pq.Array(kinds), qp.Limit, qp.Receiver,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		log.Error("querying implementations", err)
+		return nil, err
+	}
+
+	res := make(models.Implementations, len(rows))
+	for i, row := range rows {
+		res[i] = models.Implementation{
+			Symbol:    row.toModel(),
+			UpdatedAt: row.FileUpdatedAt.Local().Unix(),
+		}
+	}
+	return res, nil
+}
+
+func (row symbolRow) toModel() models.Symbol {
+	return models.Symbol{
+		Kind:        models.SymbolKind(row.Kind),
+		Name:        row.Name,
+		Receiver:    row.Receiver,
+		Signature:   row.Signature,
+		Digest:      row.Digest,
+		Line:        row.Line,
+		ProjectId:   row.ProjectID,
+		ProjectName: row.ProjectName,
+		FileId:      row.FileID,
+		FileName:    row.FileName,
+	}
+}
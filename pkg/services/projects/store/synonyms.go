@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/volatiletech/sqlboiler/v4/queries"
+	"lastimplementation.com/pkg/services/projects"
+	"lastimplementation.com/pkg/services/projects/models"
+	"lastimplementation.com/pkg/services/projects/search"
+)
+
+// maximumKnownNames bounds the names the "did you mean" suggestions are picked
+// from. The most similar names to the words are kept.
+const maximumKnownNames = 10000
+
+type synonymRow struct {
+	ID       int            `boil:"id"`
+	Term     string         `boil:"term"`
+	Synonyms pq.StringArray `boil:"synonyms"`
+}
+
+type knownNameRow struct {
+	Name string `boil:"name"`
+}
+
+// AddSynonym adds an entry to the synonyms dictionary.
+func (pr *projectsRepo) AddSynonym(ctx context.Context, synonym models.Synonym) (models.Synonym, error) {
+	log := pr.l.WithPrefix("addSynonym")
+
+	var row synonymRow
+	err := queries.Raw(`
+		INSERT INTO synonyms (term, synonyms)
+		VALUES ($1, $2)
+		RETURNING id, term, synonyms`,
+		synonym.Term, pq.Array(synonym.Synonyms),
+	).Bind(ctx, pr.db, &row)
+	if err != nil {
+		log.Error("inserting synonym", err)
+		if strings.HasSuffix(err.Error(), ErrDuplicated("synonyms_term_key")) {
+			return models.Synonym{}, projects.ErrAddSynonymDuplicatedTerm
+		}
+		return models.Synonym{}, err
+	}
+	return row.toModel(), nil
+}
+
+// GetSynonyms fetches the synonyms dictionary, by term.
+func (pr *projectsRepo) GetSynonyms(ctx context.Context) (models.Synonyms, error) {
+	log := pr.l.WithPrefix("getSynonyms")
+
+	var rows []synonymRow
+	if err := queries.Raw("SELECT id, term, synonyms FROM synonyms ORDER BY term").Bind(ctx, pr.db, &rows); err != nil {
+		log.Error("querying synonyms", err)
+		return nil, err
+	}
+	res := make(models.Synonyms, len(rows))
+	for i, row := range rows {
+		res[i] = row.toModel()
+	}
+	return res, nil
+}
+
+// UpdateSynonym replaces an entry of the synonyms dictionary.
+func (pr *projectsRepo) UpdateSynonym(ctx context.Context, synonym models.Synonym) error {
+	log := pr.l.WithPrefix("updateSynonym")
+
+	res, err := pr.db.ExecContext(ctx, `
+		UPDATE synonyms SET term = $2, synonyms = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		synonym.Id, synonym.Term, pq.Array(synonym.Synonyms),
+	)
+	if err != nil {
+		log.Error("updating synonym", err)
+		if strings.HasSuffix(err.Error(), ErrDuplicated("synonyms_term_key")) {
+			return projects.ErrAddSynonymDuplicatedTerm
+		}
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return projects.ErrSynonymNotFound
+	}
+	return nil
+}
+
+// DeleteSynonym deletes an entry of the synonyms dictionary.
+func (pr *projectsRepo) DeleteSynonym(ctx context.Context, id int) error {
+	log := pr.l.WithPrefix("deleteSynonym")
+
+	res, err := pr.db.ExecContext(ctx, "DELETE FROM synonyms WHERE id = $1", id)
+	if err != nil {
+		log.Error("deleting synonym", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return projects.ErrSynonymNotFound
+	}
+	return nil
+}
+
+// KnownNames fetches the lower cased names of the projects, tags and symbols
+// that could be a misspelling of any of the words: their length is within the
+// maximum edits of the word, and they either start with the same letter or,
+// for projects and tags, are similar by trigrams. Every word gets its own
+// subqueries, with the prefix known when planning them, so the name prefix
+// indexes and the project name trigram index are used rather than scanning
+// the tables. The tags are few enough to be compared by trigrams as well.
+func (pr *projectsRepo) KnownNames(ctx context.Context, words []string) ([]string, error) {
+	log := pr.l.WithPrefix("knownNames")
+
+	sq := &searchQuery{}
+	var selects []string
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		word, prefix := sq.arg(w), sq.arg(escapeLike(string([]rune(w)[:1])))
+		edits := search.MaximumEdits(w)
+		shortest, longest := sq.arg(len([]rune(w))-edits), sq.arg(len([]rune(w))+edits)
+		for _, source := range []struct {
+			table   string
+			trigram bool
+		}{{"projects", true}, {"tags", true}, {"code_symbols", false}} {
+			match := fmt.Sprintf("LOWER(name) LIKE %s::text || '%%'", prefix)
+			if source.trigram {
+				match = fmt.Sprintf("(%s OR name %% %s::text)", match, word)
+			}
+			selects = append(selects, fmt.Sprintf(
+				"SELECT LOWER(name) AS name, similarity(LOWER(name), %s::text) AS similarity FROM %s WHERE %s AND LENGTH(name) BETWEEN %s AND %s",
+				word, source.table, match, shortest, longest))
+		}
+	}
+	if len(selects) == 0 {
+		return nil, nil
+	}
+
+	var rows []knownNameRow
+	err := queries.Raw(fmt.Sprintf(`
+		SELECT n.name FROM (%s) n
+		GROUP BY n.name
+		ORDER BY MAX(n.similarity) DESC, n.name
+		LIMIT %s`, strings.Join(selects, " UNION ALL "), sq.arg(maximumKnownNames)),
+		sq.args...,
+	).Bind(ctx, pr.db, &rows)
+	if err != nil {
+		log.Error("querying known names", err)
+		return nil, err
+	}
+	res := make([]string, len(rows))
+	for i, row := range rows {
+		res[i] = row.Name
+	}
+	return res, nil
+}
+
+func (row synonymRow) toModel() models.Synonym {
+	return models.Synonym{Id: row.ID, Term: row.Term, Synonyms: []string(row.Synonyms)}
+}
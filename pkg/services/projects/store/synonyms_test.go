@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKnownNames(t *testing.T) {
+	pr := testRepo(t)
+	tests := []struct {
+		name  string
+		words []string
+		want  string
+	}{
+		{"same first letter", []string{"projct_v1"}, "project_v1"},
+		{"similar project name", []string{"rpoject_v1"}, "project_v1"},
+		{"tag", []string{"languge"}, "language"},
+		{"too long", []string{"project_v1_and_then_some"}, ""},
+		{"unknown", []string{"zzzz"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := pr.KnownNames(context.Background(), tt.words)
+			if err != nil {
+				t.Fatalf("KnownNames() error = %v", err)
+			}
+			got := ""
+			if len(names) > 0 {
+				got = names[0]
+			}
+			if got != tt.want {
+				t.Errorf("KnownNames(%q) = %v, want %q first", tt.words, names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package symbols
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+
+	"lastimplementation.com/pkg/services/projects/language"
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+// snippetPackage is prepended to the code files that lack a package clause, as
+// snippets usually do, so they can still be parsed.
+const snippetPackage = "package snippet\n"
+
+// Parse returns the top level declarations of a Go code file: functions,
+// methods, types and constants.
+func Parse(name, content string) ([]models.Symbol, error) {
+	fset := token.NewFileSet()
+	src, lineOffset := content, 0
+	f, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+	if err != nil && !strings.HasPrefix(strings.TrimSpace(content), "package") {
+		src, lineOffset = snippetPackage+content, -1
+		f, err = parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := digester{fset, src}
+
+	var res []models.Symbol
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			sym := models.Symbol{
+				Kind:      models.SymbolKindFunc,
+				Name:      decl.Name.Name,
+				Signature: printNode(fset, &ast.FuncDecl{Recv: decl.Recv, Name: decl.Name, Type: decl.Type}),
+				Digest:    d.digest(decl),
+				Line:      fset.Position(decl.Pos()).Line + lineOffset,
+			}
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				sym.Kind = models.SymbolKindMethod
+				sym.Receiver = printNode(fset, decl.Recv.List[0].Type)
+			}
+			res = append(res, sym)
+		case *ast.GenDecl:
+			res = append(res, genDeclSymbols(d, decl, lineOffset)...)
+		}
+	}
+	return res, nil
+}
+
+func genDeclSymbols(d digester, decl *ast.GenDecl, lineOffset int) []models.Symbol {
+	fset := d.fset
+	var res []models.Symbol
+	for _, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			res = append(res, models.Symbol{
+				Kind:      models.SymbolKindType,
+				Name:      spec.Name.Name,
+				Signature: "type " + spec.Name.Name + " " + typeSummary(fset, spec.Type),
+				Digest:    d.digest(spec),
+				Line:      fset.Position(spec.Pos()).Line + lineOffset,
+			})
+		case *ast.ValueSpec:
+			if decl.Tok != token.CONST {
+				continue
+			}
+			for _, name := range spec.Names {
+				sig := "const " + name.Name
+				if spec.Type != nil {
+					sig += " " + printNode(fset, spec.Type)
+				}
+				res = append(res, models.Symbol{
+					Kind:      models.SymbolKindConst,
+					Name:      name.Name,
+					Signature: sig,
+					Digest:    d.digest(spec),
+					Line:      fset.Position(name.Pos()).Line + lineOffset,
+				})
+			}
+		}
+	}
+	return res
+}
+
+// digester fingerprints the source code of the declarations, so changes to
+// their implementation can be told apart.
+type digester struct {
+	fset *token.FileSet
+	src  string
+}
+
+func (d digester) digest(node ast.Node) string {
+	start, end := d.fset.Position(node.Pos()).Offset, d.fset.Position(node.End()).Offset
+	sum := sha1.Sum([]byte(d.src[start:end]))
+	return hex.EncodeToString(sum[:])
+}
+
+// typeSummary prints a type expression, leaving out the fields and methods of structs and interfaces.
+func typeSummary(fset *token.FileSet, expr ast.Expr) string {
+	switch expr.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	}
+	return printNode(fset, expr)
+}
+
+func printNode(fset *token.FileSet, node interface{}) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// LastChanged returns the revision where a symbol last changed, given the
+// revisions of its code file, from newest to oldest. That is the oldest
+// revision of the latest run of revisions holding the current implementation.
+// It returns nil when the latest revision does not hold it yet.
+func LastChanged(sym models.Symbol, revisions []models.FileRevision) *models.RevisionRef {
+	var res *models.RevisionRef
+	for i, rev := range revisions {
+		// Revisions where the file did not change need no parsing again.
+		unchanged := i > 0 && rev.Name == revisions[i-1].Name && rev.Content == revisions[i-1].Content
+		if !unchanged {
+			if language.Detect(rev.Name, rev.Content) != "go" {
+				break
+			}
+			syms, err := Parse(rev.Name, rev.Content)
+			if err != nil || !hasDigest(syms, sym) {
+				break
+			}
+		}
+		res = &revisions[i].RevisionRef
+	}
+	return res
+}
+
+func hasDigest(syms []models.Symbol, sym models.Symbol) bool {
+	for _, s := range syms {
+		if s.Kind == sym.Kind && s.Name == sym.Name && s.Receiver == sym.Receiver && s.Digest == sym.Digest {
+			return true
+		}
+	}
+	return false
+}
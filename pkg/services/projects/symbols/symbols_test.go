@@ -0,0 +1,92 @@
+package symbols
+
+import (
+	"reflect"
+	"testing"
+
+	"lastimplementation.com/pkg/services/projects/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []models.Symbol
+	}{
+		{
+			"file",
+			"package server\n\nconst Port int = 80\n\ntype Server struct{ addr string }\n\nfunc NewServer() *Server { return &Server{} }\n\nfunc (s *Server) Start() error { return nil }\n\nfunc (s Server) Addr() string { return s.addr }\n",
+			[]models.Symbol{
+				{Kind: models.SymbolKindConst, Name: "Port", Signature: "const Port int", Line: 3},
+				{Kind: models.SymbolKindType, Name: "Server", Signature: "type Server struct", Line: 5},
+				{Kind: models.SymbolKindFunc, Name: "NewServer", Signature: "func NewServer() *Server", Line: 7},
+				{Kind: models.SymbolKindMethod, Name: "Start", Receiver: "*Server", Signature: "func (s *Server) Start() error", Line: 9},
+				{Kind: models.SymbolKindMethod, Name: "Addr", Receiver: "Server", Signature: "func (s Server) Addr() string", Line: 11},
+			},
+		},
+		{
+			"snippet without package clause",
+			"func (h *handler) Get() {}\n",
+			[]models.Symbol{
+				{Kind: models.SymbolKindMethod, Name: "Get", Receiver: "*handler", Signature: "func (h *handler) Get()", Line: 1},
+			},
+		},
+		{
+			"variables are left out",
+			"package server\n\nvar debug = false\n\ntype id int\n",
+			[]models.Symbol{
+				{Kind: models.SymbolKindType, Name: "id", Signature: "type id int", Line: 5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse("main.go", tt.content)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			for i := range got {
+				if got[i].Digest == "" {
+					t.Errorf("Parse() symbol %s has no digest", got[i].Name)
+				}
+				got[i].Digest = ""
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastChanged(t *testing.T) {
+	const (
+		v1 = "package server\n\nfunc (s *Server) Start() error { return nil }\n"
+		v2 = "package server\n\nfunc (s *Server) Start() error { return s.listen() }\n"
+		v3 = "package server\n\n// Start starts the server.\nfunc (s *Server) Start() error { return s.listen() }\n"
+	)
+	rev := func(number int, content string) models.FileRevision {
+		return models.FileRevision{RevisionRef: models.RevisionRef{Number: number}, Name: "server.go", Content: content}
+	}
+	current, err := Parse("server.go", v2)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		revisions []models.FileRevision
+		want      *models.RevisionRef
+	}{
+		{"changed in the latest revision", []models.FileRevision{rev(3, v2), rev(2, v1)}, &models.RevisionRef{Number: 3}},
+		{"unchanged since", []models.FileRevision{rev(4, v2), rev(3, v2), rev(2, v1)}, &models.RevisionRef{Number: 3}},
+		{"other changes to the file", []models.FileRevision{rev(4, v3), rev(3, v2), rev(2, v1)}, &models.RevisionRef{Number: 3}},
+		{"not in the latest revision", []models.FileRevision{rev(3, v1), rev(2, v2)}, nil},
+		{"no revisions", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LastChanged(current[0], tt.revisions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LastChanged() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,8 +10,10 @@ import (
 	"github.com/gorilla/mux"
 	"lastimplementation.com/internal/validate"
 	"lastimplementation.com/pkg/services/projects"
+	"lastimplementation.com/pkg/services/projects/diff"
 	"lastimplementation.com/pkg/services/projects/logger"
 	"lastimplementation.com/pkg/services/projects/models"
+	"lastimplementation.com/pkg/services/projects/search"
 	"lastimplementation.com/pkg/services/projects/store"
 )
 
@@ -21,16 +23,27 @@ type handler struct {
 }
 
 // Activate ...
-func Activate(ctx context.Context, r *mux.Router, db *sql.DB, reset bool) {
+func Activate(ctx context.Context, r *mux.Router, db *sql.DB, reset bool, searchBackend string) {
 	// Setup service.
 	l := logger.New("projects", true)
 	pdb := store.New(l, db)
-	ps := projects.New(l, pdb)
+	var opts []projects.Option
+	if searchBackend == projects.SearchBackendMemory {
+		opts = append(opts, projects.WithSearcher(search.NewMemoryIndex()))
+	}
+	ps := projects.New(l, pdb, opts...)
 	if reset {
 		if err := ps.ResetRepo(ctx); err != nil {
 			l.Error("resetting the projects service: %v", err)
 		}
 	}
+	if err := ps.MigrateRepo(ctx); err != nil {
+		l.Error("migrating the projects service: %v", err)
+	}
+	if err := ps.RebuildSearch(ctx); err != nil {
+		l.Error("building the projects search index: %v", err)
+	}
+	go ps.TrimSearchLog(ctx)
 
 	// Setup handlers.
 	ph := handler{l.WithPrefix("transport"), ps}
@@ -42,9 +55,59 @@ func Activate(ctx context.Context, r *mux.Router, db *sql.DB, reset bool) {
 	s.HandleFunc("/{id:[0-9]+}", ph.Delete).Methods("DELETE")
 	s.HandleFunc("/{id:[0-9]+}/files", ph.GetFiles).Methods("GET")
 	s.HandleFunc("/{id:[0-9]+}/files", ph.UpdateFiles).Methods("PUT", "OPTIONS")
+	s.HandleFunc("/{id:[0-9]+}/revisions", ph.GetRevisions).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/revisions/{rev:[0-9]+}/restore", ph.RestoreRevision).Methods("POST", "OPTIONS")
+	s.HandleFunc("/{id:[0-9]+}/diff", ph.GetDiff).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/revisions/{rev:[0-9]+}/files", ph.GetRevisionFiles).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/revisions/{rev:[0-9]+}/files/{fileId:[0-9]+}", ph.GetRevisionFile).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/files/{fileId:[0-9]+}/blame", ph.GetBlame).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/files/{fileId:[0-9]+}/duplicates", ph.GetDuplicates).Methods("GET")
+	s.HandleFunc("/duplicates", ph.GetDuplicatedSnippets).Methods("GET")
 	s.Use(mux.CORSMethodMiddleware(s))
 	s.Use(corsAccessHeader)
 	s.Use(jsonContentHeader)
+
+	ss := r.PathPrefix("/search").Subrouter()
+	ss.HandleFunc("/code", ph.SearchCode).Methods("GET")
+	ss.Use(corsAccessHeader)
+
+	sy := r.PathPrefix("/symbols").Subrouter()
+	sy.HandleFunc("", ph.GetSymbols).Methods("GET")
+	sy.HandleFunc("/implementations", ph.GetImplementations).Methods("GET")
+	sy.Use(corsAccessHeader)
+	sy.Use(jsonContentHeader)
+
+	sg := r.PathPrefix("/suggest").Subrouter()
+	sg.HandleFunc("", ph.Suggest).Methods("GET")
+	sg.Use(corsAccessHeader)
+	sg.Use(jsonContentHeader)
+
+	sv := r.PathPrefix("/saved-searches").Subrouter()
+	sv.HandleFunc("", ph.AddSavedSearch).Methods("POST", "OPTIONS")
+	sv.HandleFunc("", ph.GetSavedSearches).Methods("GET")
+	sv.HandleFunc("/{id:[0-9]+}", ph.DeleteSavedSearch).Methods("DELETE")
+	sv.HandleFunc("/{id:[0-9]+}/matches", ph.GetNewMatches).Methods("GET")
+	sv.Use(mux.CORSMethodMiddleware(sv))
+	sv.Use(corsAccessHeader)
+	sv.Use(jsonContentHeader)
+
+	sn := r.PathPrefix("/synonyms").Subrouter()
+	sn.HandleFunc("", ph.AddSynonym).Methods("POST", "OPTIONS")
+	sn.HandleFunc("", ph.GetSynonyms).Methods("GET")
+	sn.HandleFunc("/{id:[0-9]+}", ph.UpdateSynonym).Methods("PUT", "OPTIONS")
+	sn.HandleFunc("/{id:[0-9]+}", ph.DeleteSynonym).Methods("DELETE")
+	sn.Use(mux.CORSMethodMiddleware(sn))
+	sn.Use(corsAccessHeader)
+	sn.Use(jsonContentHeader)
+
+	sa := r.PathPrefix("/admin/search").Subrouter()
+	sa.HandleFunc("/top-queries", ph.GetTopQueries).Methods("GET")
+	sa.HandleFunc("/zero-results", ph.GetZeroResultQueries).Methods("GET")
+	sa.HandleFunc("/latency", ph.GetSearchLatency).Methods("GET")
+	sa.HandleFunc("/rebuild", ph.RebuildSearch).Methods("POST", "OPTIONS")
+	sa.Use(mux.CORSMethodMiddleware(sa))
+	sa.Use(corsAccessHeader)
+	sa.Use(jsonContentHeader)
 }
 
 // Get gets a single project.
@@ -74,11 +137,7 @@ func (ph *handler) Add(rw http.ResponseWriter, h *http.Request) {
 // GetAll gets all the projects.
 func (ph *handler) GetAll(rw http.ResponseWriter, h *http.Request) {
 	ph.l.Trace("get all projects request started")
-	qp, err := models.NewSearchQP(
-		h.FormValue("q"),
-		h.FormValue("page"),
-		h.FormValue("limit"),
-	)
+	qp, err := models.NewSearchQP(h.URL.Query())
 	if err != nil {
 		ph.l.Error("get all projects", "reading form values", err)
 		ph.writeError(rw, http.StatusBadRequest, err)
@@ -215,17 +274,585 @@ func (ph *handler) UpdateFiles(rw http.ResponseWriter, h *http.Request) {
 	rw.WriteHeader(http.StatusOK)
 }
 
+// SearchCode streams the code files lines matched by a regular expression, as newline delimited JSON.
+func (ph *handler) SearchCode(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("search code")
+	log.Trace("request started")
+	qp, err := models.NewCodeSearchQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		rw.Header().Set("Content-Type", "application/json")
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := rw.(http.Flusher)
+	streaming := false
+	summary, err := ph.ProjectsService.SearchCode(h.Context(), qp, func(file models.CodeSearchFile) error {
+		streaming = true
+		event := models.CodeSearchEvent{File: &file}
+		if err := event.ToJSON(rw); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("searching code files", err)
+		if !streaming {
+			ph.handleError(err, rw)
+		}
+		return
+	}
+	event := models.CodeSearchEvent{Summary: &summary}
+	if err := event.ToJSON(rw); err != nil {
+		log.Error("emitting summary", err)
+	}
+}
+
+// GetSymbols writes the code symbols matching a name.
+func (ph *handler) GetSymbols(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get symbols")
+	log.Trace("request started")
+	qp, err := models.NewSymbolQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	symbols, err := ph.ProjectsService.GetSymbols(context.Background(), qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := symbols.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetImplementations writes every implementation of a function, method or type, most recent first.
+func (ph *handler) GetImplementations(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get implementations")
+	log.Trace("request started")
+	qp, err := models.NewSymbolQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	impls, err := ph.ProjectsService.GetImplementations(context.Background(), qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := impls.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// Suggest writes the project, tag and code file names starting with a prefix.
+func (ph *handler) Suggest(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("suggest")
+	log.Trace("request started")
+	qp, err := models.NewSuggestQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	suggestions, err := ph.ProjectsService.Suggest(context.Background(), qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := suggestions.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// AddSavedSearch saves a projects search under a name.
+func (ph *handler) AddSavedSearch(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("add saved search")
+	log.Trace("request started")
+	var search models.SavedSearch
+	if err := search.FromJSON(h.Body); err != nil {
+		log.Error("failed to decode body", err)
+		ph.writeError(rw, http.StatusBadRequest, projects.ErrDecodeBody)
+		return
+	}
+	if err := validate.Get().Struct(search); err != nil {
+		log.Error("reading input values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := search.SearchQP(); err != nil {
+		log.Error("reading saved query", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	search, err := ph.ProjectsService.AddSavedSearch(context.Background(), search)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := search.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetSavedSearches gets all the saved searches.
+func (ph *handler) GetSavedSearches(rw http.ResponseWriter, h *http.Request) {
+	ph.l.Trace("get saved searches request started")
+	searches, err := ph.ProjectsService.GetSavedSearches(context.Background())
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := searches.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// DeleteSavedSearch deletes a saved search.
+func (ph *handler) DeleteSavedSearch(rw http.ResponseWriter, h *http.Request) {
+	ph.l.Trace("delete saved search request started")
+	id, err := idVar(mux.Vars(h))
+	if err != nil {
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	if err := ph.ProjectsService.DeleteSavedSearch(context.Background(), id); err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// GetNewMatches gets the projects matching a saved search since it was last looked at.
+func (ph *handler) GetNewMatches(rw http.ResponseWriter, h *http.Request) {
+	ph.l.Trace("get saved search new matches request started")
+	id, err := idVar(mux.Vars(h))
+	if err != nil {
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	pl, err := ph.ProjectsService.GetNewMatches(context.Background(), id)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := pl.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetRevisions writes a page of the revisions of a project, from newest to oldest.
+func (ph *handler) GetRevisions(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get project revisions")
+	log.Trace("request started")
+	id, err := idVar(mux.Vars(h))
+	if err != nil {
+		log.Error("project id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	qp, err := models.NewRevisionsQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	revisions, err := ph.ProjectsService.GetRevisions(context.Background(), id, qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := revisions.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetRevisionFiles writes the code files of a project as they were in a revision.
+func (ph *handler) GetRevisionFiles(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get revision files")
+	log.Trace("request started")
+	vars := mux.Vars(h)
+	id, err := idVar(vars)
+	if err != nil {
+		log.Error("project id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	rev, err := intVar(vars, "rev")
+	if err != nil {
+		log.Error("revision number", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	files, err := ph.ProjectsService.GetRevisionFiles(context.Background(), id, rev)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := files.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetRevisionFile writes a code file of a project as it was in a revision.
+func (ph *handler) GetRevisionFile(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get revision file")
+	log.Trace("request started")
+	vars := mux.Vars(h)
+	id, err := idVar(vars)
+	if err != nil {
+		log.Error("project id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	rev, err := intVar(vars, "rev")
+	if err != nil {
+		log.Error("revision number", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	fileId, err := intVar(vars, "fileId")
+	if err != nil {
+		log.Error("file id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	file, err := ph.ProjectsService.GetRevisionFile(context.Background(), id, rev, fileId)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := file.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// RestoreRevision brings back the code files of a project as they were in a revision.
+func (ph *handler) RestoreRevision(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("restore revision")
+	log.Trace("request started")
+	vars := mux.Vars(h)
+	id, err := idVar(vars)
+	if err != nil {
+		log.Error("project id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	rev, err := intVar(vars, "rev")
+	if err != nil {
+		log.Error("revision number", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	qp, err := models.NewRestoreQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	if err := ph.ProjectsService.RestoreRevision(context.Background(), id, rev, qp); err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// GetDiff writes the changes of the code files of a project between two
+// revisions, as JSON hunks or as a unified diff.
+func (ph *handler) GetDiff(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get diff")
+	log.Trace("request started")
+	id, err := idVar(mux.Vars(h))
+	if err != nil {
+		log.Error("project id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	qp, err := models.NewDiffQP(h.URL.Query(), h.Header.Get("Accept"))
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	d, err := ph.ProjectsService.GetDiff(context.Background(), id, qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if qp.Unified {
+		rw.Header().Set("Content-Type", models.DiffContentType)
+		if err := diff.Unified(rw, d.Files); err != nil {
+			ph.handleError(err, rw)
+		}
+		return
+	}
+	if err := d.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetBlame writes the revision that last changed each line of a code file.
+func (ph *handler) GetBlame(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get blame")
+	log.Trace("request started")
+	vars := mux.Vars(h)
+	id, err := idVar(vars)
+	if err != nil {
+		log.Error("project id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	fileId, err := intVar(vars, "fileId")
+	if err != nil {
+		log.Error("file id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	blame, err := ph.ProjectsService.GetBlame(context.Background(), id, fileId)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := blame.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetDuplicates writes the code files of other projects similar to a code file.
+func (ph *handler) GetDuplicates(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get duplicates")
+	log.Trace("request started")
+	vars := mux.Vars(h)
+	id, err := idVar(vars)
+	if err != nil {
+		log.Error("project id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	fileId, err := intVar(vars, "fileId")
+	if err != nil {
+		log.Error("file id", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	qp, err := models.NewDuplicatesQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	duplicates, err := ph.ProjectsService.GetDuplicates(context.Background(), id, fileId, qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := duplicates.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetDuplicatedSnippets writes the snippets duplicated across the most code files.
+func (ph *handler) GetDuplicatedSnippets(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get duplicated snippets")
+	log.Trace("request started")
+	qp, err := models.NewDuplicatesQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	snippets, err := ph.ProjectsService.GetDuplicatedSnippets(context.Background(), qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := snippets.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// RebuildSearch rebuilds the projects search index from the stored projects,
+// while searches keep being served from the current one.
+func (ph *handler) RebuildSearch(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("rebuild search")
+	log.Trace("request started")
+	if err := ph.ProjectsService.RebuildSearch(context.Background()); err != nil {
+		log.Error("rebuilding the projects search index", err)
+		ph.handleError(err, rw)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// GetTopQueries writes the most searched queries.
+func (ph *handler) GetTopQueries(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get top queries")
+	log.Trace("request started")
+	qp, err := models.NewAnalyticsQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	stats, err := ph.ProjectsService.GetTopQueries(context.Background(), qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := stats.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetZeroResultQueries writes the most searched queries that found nothing.
+func (ph *handler) GetZeroResultQueries(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get zero result queries")
+	log.Trace("request started")
+	qp, err := models.NewAnalyticsQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	stats, err := ph.ProjectsService.GetZeroResultQueries(context.Background(), qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := stats.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetSearchLatency writes the latency percentiles of the searches.
+func (ph *handler) GetSearchLatency(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("get search latency")
+	log.Trace("request started")
+	qp, err := models.NewAnalyticsQP(h.URL.Query())
+	if err != nil {
+		log.Error("reading form values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	stats, err := ph.ProjectsService.GetSearchLatency(context.Background(), qp)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := stats.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// AddSynonym adds an entry to the synonyms dictionary.
+func (ph *handler) AddSynonym(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("add synonym")
+	log.Trace("request started")
+	var synonym models.Synonym
+	if err := synonym.FromJSON(h.Body); err != nil {
+		log.Error("failed to decode body", err)
+		ph.writeError(rw, http.StatusBadRequest, projects.ErrDecodeBody)
+		return
+	}
+	// The synonym is normalized first, so blank and repeated synonyms do not
+	// pass the validation and then get dropped.
+	synonym.Normalize()
+	if err := validate.Get().Struct(synonym); err != nil {
+		log.Error("reading input values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	synonym, err := ph.ProjectsService.AddSynonym(context.Background(), synonym)
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := synonym.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// GetSynonyms gets the synonyms dictionary.
+func (ph *handler) GetSynonyms(rw http.ResponseWriter, h *http.Request) {
+	ph.l.Trace("get synonyms request started")
+	synonyms, err := ph.ProjectsService.GetSynonyms(context.Background())
+	if err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	if err := synonyms.ToJSON(rw); err != nil {
+		ph.handleError(err, rw)
+	}
+}
+
+// UpdateSynonym replaces an entry of the synonyms dictionary.
+func (ph *handler) UpdateSynonym(rw http.ResponseWriter, h *http.Request) {
+	log := ph.l.WithPrefix("update synonym")
+	log.Trace("request started")
+	id, err := idVar(mux.Vars(h))
+	if err != nil {
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	var synonym models.Synonym
+	if err := synonym.FromJSON(h.Body); err != nil {
+		log.Error("failed to decode body", err)
+		ph.writeError(rw, http.StatusBadRequest, projects.ErrDecodeBody)
+		return
+	}
+	// The synonym is normalized first, so blank and repeated synonyms do not
+	// pass the validation and then get dropped.
+	synonym.Normalize()
+	if err := validate.Get().Struct(synonym); err != nil {
+		log.Error("reading input values", err)
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	synonym.Id = id
+	if err := ph.ProjectsService.UpdateSynonym(context.Background(), synonym); err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// DeleteSynonym deletes an entry of the synonyms dictionary.
+func (ph *handler) DeleteSynonym(rw http.ResponseWriter, h *http.Request) {
+	ph.l.Trace("delete synonym request started")
+	id, err := idVar(mux.Vars(h))
+	if err != nil {
+		ph.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	if err := ph.ProjectsService.DeleteSynonym(context.Background(), id); err != nil {
+		ph.handleError(err, rw)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
 func idVar(vars map[string]string) (int, error) {
-	idv, ok := vars["id"]
+	return intVar(vars, "id")
+}
+
+func intVar(vars map[string]string, name string) (int, error) {
+	idv, ok := vars[name]
 	if !ok {
 		return -1, nil
 	}
 	id, err := strconv.Atoi(idv)
 	if err != nil {
-		return -1, fmt.Errorf("invalid id %d: invalid integer", id)
+		return -1, fmt.Errorf("invalid %s %d: invalid integer", name, id)
 	}
 	if id < 1 {
-		return -1, fmt.Errorf("invalid id %d: minimum 1", id)
+		return -1, fmt.Errorf("invalid %s %d: minimum 1", name, id)
 	}
 	return id, nil
 }
@@ -240,9 +867,11 @@ func (ph *handler) handleError(err error, rw http.ResponseWriter) {
 	switch outboundErr {
 	case projects.ErrProjectTimeout:
 		ph.writeResponse(rw, http.StatusRequestTimeout, outboundErr)
-	case projects.ErrProjectNotFound:
+	case projects.ErrProjectNotFound, projects.ErrSavedSearchNotFound, projects.ErrCodeFileNotFound, projects.ErrSynonymNotFound,
+		projects.ErrRevisionNotFound:
 		ph.writeResponse(rw, http.StatusNotFound, outboundErr)
-	case projects.ErrAddProjectDuplicatedName:
+	case projects.ErrAddProjectDuplicatedName, projects.ErrAddSavedSearchDuplicatedName, projects.ErrAddSynonymDuplicatedTerm,
+		projects.ErrTooManyCodeFiles:
 		ph.writeResponse(rw, http.StatusBadRequest, outboundErr)
 	default:
 		ph.writeResponse(rw, http.StatusInternalServerError, outboundErr)